@@ -47,5 +47,10 @@ func main() {
 		kong.UsageOnError(),
 	)
 
+	// Make an explicit manifest available to the config (and child qgo processes)
+	if len(root.ManifestFile) > 0 {
+		os.Setenv("QGO_MANIFEST", root.ManifestFile)
+	}
+
 	ctx.Run(cmd)
 }
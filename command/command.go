@@ -9,16 +9,24 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/quikdev/go/config"
 	"github.com/quikdev/go/util"
 )
 
+// Step describes the execution of a command (one part of a "&&" chain).
+// Injected commands receive the step they run before/after.
+type Step struct {
+	Index    int
+	Duration time.Duration // Zero before the command runs
+	Success  bool
+}
+
+// InjectedCommand runs before or after the command at Position.
 type InjectedCommand struct {
 	Position int
-	Command  string
-	Noerror  bool
 	Before   bool
+	Run      func(step Step)
 }
 
 type Command struct {
@@ -26,6 +34,7 @@ type Command struct {
 	color    map[string][]int
 	pid      int
 	injected []InjectedCommand
+	env      []string
 }
 
 func New() *Command {
@@ -36,20 +45,20 @@ func New() *Command {
 	}
 }
 
-func (cmd *Command) InjectCommand(position int, command string, before bool, noerr ...bool) {
-	noerror := false
-	if len(noerr) > 0 {
-		noerror = noerr[0]
-	}
-
+func (cmd *Command) InjectCommand(position int, before bool, run func(step Step)) {
 	cmd.injected = append(cmd.injected, InjectedCommand{
 		Position: position,
-		Command:  command,
-		Noerror:  noerror,
 		Before:   before,
+		Run:      run,
 	})
 }
 
+// SetEnv sets the environment variables (NAME=value) added to the
+// environment of the process when the command runs.
+func (cmd *Command) SetEnv(vars ...string) {
+	cmd.env = vars
+}
+
 func (cmd *Command) Add(value ...string) {
 	cmd.str = append(cmd.str, value...)
 }
@@ -84,14 +93,24 @@ func (cmd *Command) PID() int {
 	return cmd.pid
 }
 
+// Run runs the command (exits when a command fails).
 func (cmd *Command) Run(cwd ...string) {
-	commands := split(cmd.str, "&&")
+	if _, ok := cmd.run(cwd); !ok {
+		os.Exit(1)
+	}
+}
+
+// Try runs the command like Run, but returns the error output (ex: compiler
+// errors) instead of exiting when a command fails.
+func (cmd *Command) Try(cwd ...string) (string, bool) {
+	return cmd.run(cwd)
+}
 
-	cfg := config.New()
-	vars := cfg.GetEnvVarList()
+func (cmd *Command) run(cwd []string) (string, bool) {
+	commands := split(cmd.str, "&&")
 
 	for index, code := range commands {
-		cmd.runInjectedCommand(index, true)
+		cmd.runInjectedCommand(Step{Index: index, Success: true}, true)
 
 		args := make([]string, len(code))
 		for i, line := range code {
@@ -112,7 +131,7 @@ func (cmd *Command) Run(cwd ...string) {
 		c := exec.Command(code[0], args[1:]...)
 
 		// Add manifest/package environment variables to command execution context
-		c.Env = append(os.Environ(), vars...)
+		c.Env = append(os.Environ(), cmd.env...)
 
 		curr, _ := os.Getwd()
 		if len(cwd) > 0 {
@@ -125,18 +144,19 @@ func (cmd *Command) Run(cwd ...string) {
 		stdout, err := c.StdoutPipe()
 		if err != nil {
 			fmt.Printf("Error creating stdout pipe: %v\n", err)
-			return
+			return err.Error(), false
 		}
 
 		stderr, err := c.StderrPipe()
 		if err != nil {
 			fmt.Printf("Error creating stderr pipe: %v\n", err)
-			return
+			return err.Error(), false
 		}
 
+		start := time.Now()
 		if err := c.Start(); err != nil {
 			fmt.Printf("Error starting command: %v\n", err)
-			return
+			return err.Error(), false
 		}
 
 		cmd.pid = c.Process.Pid
@@ -145,6 +165,7 @@ func (cmd *Command) Run(cwd ...string) {
 
 		// Function to read and print output from a pipe
 		exit := false
+		var errors strings.Builder
 		stream := func(pipe io.Reader, streamType string) {
 			defer wg.Done()
 			size := 0
@@ -153,6 +174,9 @@ func (cmd *Command) Run(cwd ...string) {
 				txt := scanner.Text()
 				fmt.Println(txt)
 				size += len(txt)
+				if streamType == "stderr" {
+					errors.WriteString(txt + "\n")
+				}
 			}
 
 			if streamType == "stderr" && size > 0 {
@@ -165,15 +189,17 @@ func (cmd *Command) Run(cwd ...string) {
 		go stream(stderr, "stderr")
 
 		// Wait for the command to finish
-		c.Wait()
+		err = c.Wait()
 		wg.Wait()
 
-		cmd.runInjectedCommand(index, false)
+		cmd.runInjectedCommand(Step{Index: index, Duration: time.Since(start), Success: err == nil && !exit}, false)
 
 		if exit {
-			os.Exit(1)
+			return errors.String(), false
 		}
 	}
+
+	return "", true
 }
 
 // func (cmd *Command) displayInjectedCommand(index int, command string) string {
@@ -190,15 +216,10 @@ func (cmd *Command) Run(cwd ...string) {
 // 	return strings.ReplaceAll(strings.ReplaceAll(command, "|\n&&", "\n&&"), "|", "\n&&")
 // }
 
-func (cmd *Command) runInjectedCommand(index int, before bool) {
+func (cmd *Command) runInjectedCommand(step Step, before bool) {
 	for _, ic := range cmd.injected {
-		if ic.Position == index && ic.Before == before {
-			if ic.Noerror {
-				util.BailOnError(util.StreamNoStdErr(ic.Command))
-			} else {
-				util.BailOnError(util.Stream(ic.Command))
-			}
-			fmt.Println("")
+		if ic.Position == step.Index && ic.Before == before {
+			ic.Run(step)
 		}
 	}
 }
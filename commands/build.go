@@ -6,7 +6,9 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/quikdev/go/command"
 	"github.com/quikdev/go/context"
+	"github.com/quikdev/go/hook"
 	"github.com/quikdev/go/util"
 
 	goupx "github.com/alegrey91/go-upx"
@@ -31,12 +33,56 @@ type Build struct {
 	Profile     []string `name:"profile" optional:"" help:"Name of the manifest.json profile attribute to apply."`
 	PreBuild    []string `name:"prebuild" optional:"" help:"Run a command before building the application."`
 	PostBuild   []string `name:"postbuild" optional:"" help:"Run a command after building the application."`
-	File        string   `arg:"source" optional:"" help:"Go source file (ex: main.go)"`
+	All         bool     `name:"all" type:"bool" help:"Build every module (with a manifest) in the go.work workspace, in dependency order."`
+	Jobs        int      `name:"jobs" short:"j" help:"The number of modules to build in parallel with --all (defaults to the number of CPUs)."`
+	SBOM        string   `name:"sbom" enum:",cyclonedx,spdx" default:"" help:"Write an SBOM (cyclonedx or spdx) and the build provenance next to the binary."`
+	Verify      bool     `name:"verify-reproducible" type:"bool" help:"Build twice (in separate temporary directories) and verify that the outputs are identical."`
+	Export      string   `name:"export" type:"string" help:"Export a deployable static site (WASM only) to this directory, with fingerprinted assets."`
+	File        string   `arg:"source" optional:"" help:"Go source file (ex: main.go) or the name of a build target (defaults to all targets)"`
 	// Container string `name:"container" default:"docker" type:"string" enum:"docker,podman" help:"The containerization technology to build with"`
+
+	outputs map[string]string // Target that built each output file
+	analyze bool              // Build for qgo size (uncompressed, the size budget is enforced by the analysis)
 }
 
 func (b *Build) Run(c *Context) error {
+	if b.All {
+		return runWorkspace(b.Jobs, true)
+	}
+
 	ctx := context.New(b.Profile...)
+
+	// Build the named target, or every target defined in the manifest
+	targets := ctx.GetConfig().Targets()
+	if len(targets) > 0 && len(b.File) > 0 && !strings.HasSuffix(b.File, ".go") {
+		targets = []string{b.File}
+	}
+
+	if len(targets) == 0 {
+		return b.build(c, ctx)
+	}
+
+	b.outputs = map[string]string{}
+	for i, name := range targets {
+		target, err := ctx.ForTarget(name)
+		if err != nil {
+			util.Stderr(err, true)
+		}
+
+		if i > 0 {
+			fmt.Println("")
+		}
+		util.Stdout(fmt.Sprintf("# %s target\n", name))
+
+		if err := b.build(c, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Build) build(c *Context, ctx *context.Context) error {
 	ctx.Configure()
 
 	if len(strings.TrimSpace(ctx.InputFile())) == 0 {
@@ -48,6 +94,11 @@ func (b *Build) Run(c *Context) error {
 		}
 	}
 
+	if b.Verify {
+		util.BailOnError(verifyReproducible(ctx, b.File))
+		return nil
+	}
+
 	if b.IgnoreCache {
 		ctx.IgnoreCache = b.IgnoreCache
 	}
@@ -60,14 +111,26 @@ func (b *Build) Run(c *Context) error {
 		ctx.Tiny = b.Tiny
 	}
 
+	if len(b.SBOM) > 0 {
+		ctx.SBOMFormat = b.SBOM
+	}
+
 	if b.WASM {
+		ctx.WASM = true
 		ctx.OS = []string{"js"}
 	} else {
 		ctx.OS = b.OS
 	}
 
-	// Autorecognize UPX support
-	if b.Compress == util.EmptyBool {
+	// Autorecognize UPX support (symbols cannot be read from compressed
+	// executables, so they are not compressed for an analysis, and a cached
+	// executable may be compressed)
+	if b.analyze {
+		if b.Compress || ctx.UPX {
+			ctx.IgnoreCache = true
+		}
+		b.Compress = false
+	} else if b.Compress == util.EmptyBool {
 		b.Compress = ctx.UPX
 	}
 
@@ -81,17 +144,45 @@ func (b *Build) Run(c *Context) error {
 		ctx.GCCGoFlags.Add("-w")
 	}
 
+	// Targets must not overwrite each other's output
+	if b.outputs != nil {
+		if other, exists := b.outputs[ctx.Output()]; exists {
+			util.Stderr(fmt.Sprintf("the %s and %s targets have the same output (%s) - set a different \"name\" or \"output\" for each target\n", other, ctx.Target, ctx.Output()), true)
+		}
+		b.outputs[ctx.Output()] = ctx.Target
+	}
+
+	// Generate the build information package (when enabled)
+	if !b.DryRun {
+		util.BailOnError(ctx.GenerateBuildInfo())
+	}
+
+	// Warn about (or stop on) variables that cannot be linked
+	ctx.ValidateVariables()
+
+	// Binaries are described when they are built, so a cached binary that
+	// is not described yet is rebuilt (a compressed binary cannot be read)
+	sbom, err := ctx.SBOM()
+	util.BailOnError(err)
+	if sbom != nil && !util.FileExists(ctx.SBOMFiles(sbom)[0]) {
+		ctx.IgnoreCache = true
+	}
+
 	// Run this before go mod tidy to determine whether the output is cached
 	cmd := ctx.BuildCommand()
 	if c.Debug {
 		b.Tips = true
 	}
 
-	if ctx.PreBuild != nil {
-		for _, precmd := range ctx.PreBuild {
-			util.BailOnError(util.Stream(precmd))
-			fmt.Println("")
-		}
+	for _, precmd := range b.PreBuild {
+		ctx.AddHook(hook.PreBuild, precmd)
+	}
+	for _, postcmd := range b.PostBuild {
+		ctx.AddHook(hook.PostBuild, postcmd)
+	}
+
+	if !b.DryRun {
+		ctx.RunHooks(ctx.Event(hook.PreBuild))
 	}
 
 	if !b.DryRun {
@@ -127,15 +218,78 @@ func (b *Build) Run(c *Context) error {
 			os.Setenv("GOGC", current)
 		}
 
-		cmd.Run(ctx.CWD)
+		// Optimize web assemblies before the post-build hooks
+		if !ctx.Cached {
+			injectWASMOptimization(ctx, cmd, 0)
+		}
+
+		// Enforce the size budget (before the binary is exported, compressed,
+		// or deployed by the post-build hooks)
+		budget, err := parseSizeBudget(ctx)
+		util.BailOnError(err)
+		enforce := func() {
+			if budget != nil && !b.analyze {
+				report, err := analyzeSize(ctx, budget.Detailed())
+				util.BailOnError(err)
+				enforceSizeBudget(budget, report)
+			}
+		}
+		if !ctx.Cached {
+			cmd.InjectCommand(0, false, func(step command.Step) {
+				if step.Success {
+					enforce()
+				}
+			})
+		}
+
+		// Export the static site (before the post-build hooks, which may deploy it)
+		export := func() {
+			util.Stdout(fmt.Sprintf("\n# exporting static site to %s\n", b.Export))
+			util.BailOnError(exportSite(ctx, b.Export))
+		}
+		if len(b.Export) > 0 {
+			if !ctx.Browser() {
+				util.Stderr("--export ignored (only browser web assemblies can be exported)\n")
+			} else if !ctx.Cached {
+				cmd.InjectCommand(0, false, func(step command.Step) {
+					if step.Success {
+						export()
+					}
+				})
+			}
+		}
 
-		if ctx.PostBuild != nil {
-			for i, postcmd := range ctx.PostBuild {
-				util.BailOnError(util.Stream(postcmd))
-				if i < (len(ctx.PostBuild) - 1) {
+		// Describe the binary (before the post-build hooks, which may publish
+		// it). The build information cannot be read once the binary is
+		// compressed, so only the hashes are updated after compression.
+		if sbom != nil && !ctx.Cached {
+			cmd.InjectCommand(0, false, func(step command.Step) {
+				if step.Success {
 					fmt.Println("")
+					util.BailOnError(ctx.WriteSBOM(sbom, cmd.String()))
 				}
+			})
+		}
+
+		// Post-build hooks receive the outcome of the build
+		injectPostBuildHooks(ctx, cmd, 0, false)
+
+		// Keep wasm_exec.js in sync with the toolchain (before the static site
+		// is exported)
+		if ctx.WASM && !ctx.WASI() {
+			if _, err := ctx.UpdateWASMExec(); err != nil {
+				util.Stderr(err)
+			}
+		}
+
+		cmd.Run(ctx.CWD)
+
+		if ctx.Cached {
+			enforce()
+			if len(b.Export) > 0 && ctx.Browser() {
+				export()
 			}
+			ctx.RunHooks(ctx.Event(hook.PostBuild))
 		}
 
 		if b.Compress {
@@ -151,7 +305,47 @@ func (b *Build) Run(c *Context) error {
 			util.HighlightCommand("upx", upx.GetArgs()...)
 			util.Stdout(fmt.Sprintf("  ↳ Name: %v\n  ↳ Format: %v\n  ↳ Original: %v\n  ↳ Compressed: %v\n  ↳ Ratio: %v%% of original\n", upx.CmdExecution.GetName(), upx.CmdExecution.GetFormat(), humanize.Bytes(upx.CmdExecution.GetOriginalFileSize()), humanize.Bytes(upx.CmdExecution.GetCompressedFileSize()), upx.CmdExecution.GetRatio()))
 		}
+
+		// Reproducible outputs have a fixed modification time and permissions
+		util.BailOnError(ctx.NormalizeOutput())
+
+		// The SBOM and provenance describe the compressed binary
+		if sbom != nil && b.Compress && !ctx.Cached {
+			util.BailOnError(ctx.UpdateSBOM(sbom))
+		}
 	}
 
 	return nil
 }
+
+// injectWASMOptimization optimizes a web assembly (wasm-opt) after a
+// successful build step at the position.
+func injectWASMOptimization(ctx *context.Context, cmd *command.Command, position int) {
+	if !ctx.WASM || ctx.WASMOptimize == nil {
+		return
+	}
+
+	cmd.InjectCommand(position, false, func(step command.Step) {
+		if step.Success {
+			util.BailOnError(ctx.OptimizeWASM())
+		}
+	})
+}
+
+// injectPostBuildHooks runs the post-build hooks after the build step at the
+// position, providing the outcome (duration and success) of the build.
+func injectPostBuildHooks(ctx *context.Context, cmd *command.Command, position int, hide bool) {
+	if len(ctx.PostBuild) == 0 {
+		return
+	}
+
+	cmd.InjectCommand(position, false, func(step command.Step) {
+		event := ctx.Event(hook.PostBuild)
+		event.DurationMS = step.Duration.Milliseconds()
+		event.Success = step.Success
+		if !hide {
+			fmt.Println("")
+		}
+		ctx.RunHooks(event, hide)
+	})
+}
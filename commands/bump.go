@@ -3,7 +3,6 @@ package commands
 import (
 	"fmt"
 	"os"
-	"regexp"
 
 	"github.com/Masterminds/semver"
 	"github.com/quikdev/go/context"
@@ -63,17 +62,11 @@ func (b *Bump) Run(c *Context) error {
 		original = s
 	}
 
-	// Read in file
-	input, err := cfg.Raw()
-	if err != nil {
+	// Update the version in place (preserves comments & formatting)
+	if err := cfg.Update("version", newVersion.String()); err != nil {
 		util.Stderr(err, true)
 	}
 
-	regex := regexp.MustCompile(`"version"\s*:\s*"([^"]+)"`)
-	output := regex.ReplaceAllString(string(input), `"version": "`+newVersion.String()+`"`)
-
-	os.WriteFile(cfg.File(), []byte(output), os.ModePerm)
-
 	fmt.Printf("bumped %s → %s\n", original.(string), util.Highlighter(newVersion.String()))
 
 	return nil
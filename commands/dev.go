@@ -0,0 +1,849 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fsnotify/fsnotify"
+	"github.com/quikdev/go/context"
+	"github.com/quikdev/go/util"
+)
+
+type Dev struct {
+	Profile  []string `name:"profile" optional:"" help:"Name of the manifest.json profile attribute to apply."`
+	Services []string `arg:"service" optional:"" help:"Name(s) of the services to run (defaults to all). Dependencies are started automatically."`
+}
+
+// devService is a process managed by the dev supervisor. It runs a build
+// target (or the app, when no targets are defined) or a script.
+type devService struct {
+	Name      string
+	Target    string
+	App       bool // The manifest app (no targets)
+	Script    string
+	Args      []string
+	Env       map[string]string
+	DependsOn []string
+	Restart   string // on-failure (default), always, or never
+	Watch     []string
+
+	// Readiness check (TCP port and/or HTTP URL)
+	ReadyPort    int
+	ReadyHTTP    string
+	ReadyTimeout time.Duration
+
+	// Reverse proxy hiding restarts (optional)
+	proxy *devProxy
+
+	wasm     bool
+	binary   string
+	launcher []string // Command running the binary (ex: a WASI runtime)
+	prefix   string
+	output   *prefixWriter
+
+	reloadmu   sync.Mutex // Serializes rebuilds/restarts
+	mu         sync.Mutex
+	cmd        *exec.Cmd
+	exited     chan struct{} // Closed when the process exits
+	generation int
+	backoff    time.Duration
+	ready      chan struct{}
+	readyonce  sync.Once
+}
+
+const (
+	minbackoff = time.Second
+	maxbackoff = 30 * time.Second
+
+	// Processes that run at least this long reset the restart backoff
+	stableuptime = 10 * time.Second
+)
+
+type supervisor struct {
+	ctx      *context.Context
+	services []*devService
+	profiles []string
+	exe      string
+	mu       sync.Mutex // Serializes output
+	closing  bool
+	closemu  sync.Mutex
+}
+
+func (d *Dev) Run(c *Context) error {
+	ctx := context.New(d.Profile...)
+
+	services, err := devServices(ctx, d.Services)
+	if err != nil {
+		util.Stderr(err, true)
+	}
+
+	exe, err := os.Executable()
+	util.BailOnError(err)
+
+	s := &supervisor{ctx: ctx, services: services, profiles: d.Profile, exe: exe}
+
+	return s.run()
+}
+
+// devServices reads the services from the "dev" manifest attribute, returning
+// the requested services (and their dependencies) in dependency order.
+func devServices(ctx *context.Context, names []string) ([]*devService, error) {
+	cfg := ctx.GetConfig()
+
+	definitions := map[string]interface{}{}
+	if value, exists := cfg.Get("dev"); exists {
+		if defs, ok := value.(map[string]interface{}); ok {
+			definitions = defs
+		} else {
+			return []*devService{}, fmt.Errorf(`invalid "dev" value (expected an object of services)`)
+		}
+	}
+
+	// Without configuration, every build target (or the app) is a service
+	if len(definitions) == 0 {
+		targets := cfg.Targets()
+		if len(targets) == 0 {
+			definitions["app"] = map[string]interface{}{}
+		}
+		for _, target := range targets {
+			definitions[target] = map[string]interface{}{"target": target}
+		}
+	}
+
+	// Scripts are interpolated when a service uses them
+	scripts := map[string]interface{}{}
+	if value, exists := cfg.GetRaw("scripts"); exists {
+		if s, ok := value.(map[string]interface{}); ok {
+			scripts = s
+		}
+	}
+
+	byname := map[string]*devService{}
+	for name, definition := range definitions {
+		def, ok := definition.(map[string]interface{})
+		if !ok {
+			return []*devService{}, fmt.Errorf(`the "%s" dev service must be an object`, name)
+		}
+
+		svc := newDevService(name)
+
+		if target, ok := def["target"].(string); ok {
+			svc.Target = target
+		}
+
+		if script, ok := def["script"].(string); ok {
+			// A script name from the manifest, or a command
+			if command, exists := scripts[script].(string); exists {
+				resolved, err := cfg.Interpolate(command)
+				if err != nil {
+					return []*devService{}, fmt.Errorf(`%v (in "scripts.%s")`, err, script)
+				}
+				svc.Script = resolved
+			} else {
+				svc.Script = script
+			}
+		}
+
+		if len(svc.Target) > 0 && len(svc.Script) > 0 {
+			return []*devService{}, fmt.Errorf(`the "%s" dev service can have a target or a script (not both)`, name)
+		}
+		svc.App = len(svc.Target) == 0 && len(svc.Script) == 0
+
+		if svc.App && len(cfg.Targets()) > 0 {
+			if !util.InSlice[string](name, cfg.Targets()) {
+				return []*devService{}, fmt.Errorf(`the "%s" dev service requires a target or script`, name)
+			}
+			svc.Target, svc.App = name, false
+		}
+
+		svc.Args = stringList(def["args"])
+		svc.DependsOn = stringList(def["depends_on"])
+		svc.Watch = stringList(def["watch"])
+
+		if env, ok := def["env"].(map[string]interface{}); ok {
+			for key, value := range env {
+				svc.Env[key] = fmt.Sprintf("%v", value)
+			}
+		}
+
+		if restart, ok := def["restart"].(string); ok {
+			switch restart {
+			case "always", "on-failure", "never":
+				svc.Restart = restart
+			default:
+				return []*devService{}, fmt.Errorf(`invalid restart policy "%s" for the "%s" dev service (expected always, on-failure, or never)`, restart, name)
+			}
+		}
+
+		if ready, ok := def["ready"].(map[string]interface{}); ok {
+			if port, ok := ready["port"].(float64); ok {
+				svc.ReadyPort = int(port)
+			}
+			if url, ok := ready["http"].(string); ok {
+				svc.ReadyHTTP = url
+			}
+			if timeout, ok := ready["timeout"].(string); ok {
+				duration, err := time.ParseDuration(timeout)
+				if err != nil {
+					return []*devService{}, fmt.Errorf(`invalid ready timeout for the "%s" dev service: %v`, name, err)
+				}
+				svc.ReadyTimeout = duration
+			}
+		}
+
+		if value, exists := def["proxy"]; exists {
+			proxy, err := parseProxy(value)
+			if err != nil {
+				return []*devService{}, fmt.Errorf(`the "%s" dev service: %v`, name, err)
+			}
+			svc.proxy = proxy
+		}
+
+		byname[name] = svc
+	}
+
+	for _, svc := range byname {
+		for _, dep := range svc.DependsOn {
+			if _, exists := byname[dep]; !exists {
+				return []*devService{}, fmt.Errorf(`the "%s" dev service depends on "%s", which does not exist`, svc.Name, dep)
+			}
+		}
+	}
+
+	// Requested services (and their dependencies), in dependency order
+	if len(names) == 0 {
+		for name := range byname {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	ordered := []*devService{}
+	state := map[string]int{} // 1 = visiting, 2 = visited
+	var visit func(name string, chain []string) error
+	visit = func(name string, chain []string) error {
+		svc, exists := byname[name]
+		if !exists {
+			available := make([]string, 0, len(byname))
+			for n := range byname {
+				available = append(available, n)
+			}
+			sort.Strings(available)
+			return fmt.Errorf(`dev service "%s" not found - please use one of the following: %s`, name, strings.Join(available, ", "))
+		}
+
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("circular dev service dependency: %s", strings.Join(append(chain, name), " → "))
+		case 2:
+			return nil
+		}
+
+		state[name] = 1
+		deps := append([]string{}, svc.DependsOn...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := visit(dep, append(chain, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		ordered = append(ordered, svc)
+
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, []string{}); err != nil {
+			return ordered, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// newDevService creates a service with the default settings.
+func newDevService(name string) *devService {
+	return &devService{
+		Name:         name,
+		Env:          map[string]string{},
+		Restart:      "on-failure",
+		ReadyTimeout: 30 * time.Second,
+		backoff:      minbackoff,
+		ready:        make(chan struct{}),
+	}
+}
+
+// stringList converts a string or list of strings.
+func stringList(value interface{}) []string {
+	result := []string{}
+	switch v := value.(type) {
+	case string:
+		result = append(result, v)
+	case []interface{}:
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+	}
+
+	return result
+}
+
+func (s *supervisor) run() error {
+	width := 0
+	for _, svc := range s.services {
+		if len(svc.Name) > width {
+			width = len(svc.Name)
+		}
+	}
+
+	for i, svc := range s.services {
+		svc.prefix = prefixFor(svc.Name, width, i)
+		svc.output = newPrefixWriter(svc.prefix, &s.mu)
+
+		if err := s.configure(svc); err != nil {
+			util.Stderr(err, true)
+		}
+	}
+
+	names := make([]string, len(s.services))
+	for i, svc := range s.services {
+		names[i] = svc.Name
+	}
+	util.Stdout(fmt.Sprintf("# starting %s (ctrl+c to stop)\n\n", strings.Join(names, ", ")))
+
+	// Stop everything on ctrl+c
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	for _, svc := range s.services {
+		go s.launch(svc)
+	}
+
+	<-signals
+	s.shutdown()
+
+	return nil
+}
+
+// configure starts the proxy of the service (if any), and identifies the
+// binary of build targets and the watched files.
+func (s *supervisor) configure(svc *devService) error {
+	if svc.proxy != nil {
+		if err := svc.proxy.start(); err != nil {
+			return fmt.Errorf(`cannot start the proxy of the "%s" dev service: %v`, svc.Name, err)
+		}
+		svc.Env[svc.proxy.Env] = fmt.Sprintf("%d", svc.proxy.Upstream)
+
+		// The proxy forwards requests once the app accepts connections
+		if svc.ReadyPort == 0 {
+			svc.ReadyPort = svc.proxy.Upstream
+		}
+		s.log(svc, fmt.Sprintf("proxy listening on http://localhost:%d (app on port %d)", svc.proxy.Port, svc.proxy.Upstream))
+	}
+
+	// An HTTP path is relative to the port (ex: "/health")
+	if strings.HasPrefix(svc.ReadyHTTP, "/") {
+		if svc.ReadyPort == 0 {
+			return fmt.Errorf(`the "%s" dev service ready check requires a port for the %s path`, svc.Name, svc.ReadyHTTP)
+		}
+		svc.ReadyHTTP = fmt.Sprintf("http://localhost:%d%s", svc.ReadyPort, svc.ReadyHTTP)
+	}
+
+	if len(svc.Script) > 0 {
+		return nil
+	}
+
+	ctx := s.ctx
+	if len(svc.Target) > 0 {
+		target, err := s.ctx.ForTarget(svc.Target)
+		if err != nil {
+			return err
+		}
+		ctx = target
+	} else {
+		ctx = context.FromConfig(s.ctx.GetConfig())
+	}
+	ctx.Configure()
+
+	svc.wasm = ctx.WASM && !ctx.WASI()
+	svc.binary = ctx.Output()
+
+	// WASI modules are run by a WASI runtime
+	if ctx.WASI() {
+		launcher, err := ctx.WASIRuntime()
+		if err != nil {
+			return err
+		}
+		svc.launcher = launcher
+	}
+
+	vars, err := ctx.GetConfig().Environment()
+	if err != nil {
+		return err
+	}
+	for _, v := range vars {
+		if _, exists := svc.Env[v.Name]; !exists && v.Source != "process" {
+			svc.Env[v.Name] = v.Value
+		}
+	}
+
+	if len(svc.Watch) == 0 {
+		svc.Watch = []string{"**/*.go", "*.go"}
+		if reload, exists := ctx.GetConfig().Get("livereload"); exists {
+			svc.Watch = stringList(reload)
+		}
+	}
+
+	return nil
+}
+
+func (s *supervisor) log(svc *devService, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Printf("%s %s\n", svc.prefix, color.New(color.Faint).Sprint(msg))
+}
+
+func (s *supervisor) isClosing() bool {
+	s.closemu.Lock()
+	defer s.closemu.Unlock()
+	return s.closing
+}
+
+// launch waits for the dependencies of a service to be ready, then builds
+// (if necessary), starts, and watches the service.
+func (s *supervisor) launch(svc *devService) {
+	for _, name := range svc.DependsOn {
+		for _, dep := range s.services {
+			if dep.Name == name {
+				select {
+				case <-dep.ready:
+				default:
+					s.log(svc, "waiting for "+name)
+					<-dep.ready
+				}
+			}
+		}
+	}
+
+	if len(svc.Watch) > 0 && !svc.wasm {
+		go s.watch(svc)
+	}
+
+	svc.reloadmu.Lock()
+	built := s.build(svc)
+	svc.reloadmu.Unlock()
+
+	if built {
+		s.start(svc)
+	} else {
+		s.log(svc, "build failed (waiting for changes)")
+		svc.readyonce.Do(func() { close(svc.ready) })
+	}
+}
+
+// build builds a target (or the app) using qgo build.
+func (s *supervisor) build(svc *devService) bool {
+	if len(svc.Script) > 0 || svc.wasm {
+		return true
+	}
+
+	args := []string{"build"}
+	if len(svc.Target) > 0 {
+		args = append(args, svc.Target)
+	}
+	for _, profile := range s.profiles {
+		args = append(args, "--profile", profile)
+	}
+
+	// Each build has its own writer (the running process keeps writing to
+	// the service output). Build output is also displayed in browsers
+	// connected to the proxy (on failure).
+	var output bytes.Buffer
+	console := newPrefixWriter(svc.prefix, &s.mu)
+	// The environment includes an explicit manifest (QGO_MANIFEST), so the
+	// build uses the same manifest as the supervisor.
+	cmd := exec.Command(s.exe, args...)
+	cmd.Env = os.Environ()
+	cmd.Stdout = io.MultiWriter(console, &output)
+	cmd.Stderr = cmd.Stdout
+
+	err := cmd.Run()
+	console.Close()
+
+	if svc.proxy != nil {
+		if err != nil {
+			svc.proxy.reloader.Error(output.String())
+		} else {
+			svc.proxy.reloader.Clear()
+		}
+	}
+
+	return err == nil
+}
+
+// command creates the process of a service.
+func (s *supervisor) command(svc *devService) *exec.Cmd {
+	var cmd *exec.Cmd
+	switch {
+	case len(svc.Script) > 0:
+		script := strings.Join(append([]string{svc.Script}, svc.Args...), " ")
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/c", script)
+		} else {
+			cmd = exec.Command("sh", "-c", script)
+		}
+	case svc.wasm:
+		// WASM apps are served (and live-reloaded) by qgo run
+		args := []string{"run"}
+		if len(svc.Target) > 0 {
+			args = append(args, svc.Target)
+		}
+		for _, profile := range s.profiles {
+			args = append(args, "--profile", profile)
+		}
+		cmd = exec.Command(s.exe, args...)
+	default:
+		args := append(append([]string{}, svc.launcher...), svc.binary)
+		args = append(args, svc.Args...)
+		cmd = exec.Command(args[0], args[1:]...)
+	}
+
+	cmd.Env = os.Environ()
+	for key, value := range svc.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Stdout = svc.output
+	cmd.Stderr = svc.output
+	isolate(cmd)
+
+	return cmd
+}
+
+// start starts the process of a service, restarting it when it exits
+// unexpectedly (according to the restart policy).
+func (s *supervisor) start(svc *devService) {
+	if s.isClosing() {
+		return
+	}
+
+	cmd := s.command(svc)
+
+	started := time.Now()
+	if err := cmd.Start(); err != nil {
+		s.log(svc, fmt.Sprintf("failed to start: %v", err))
+		svc.readyonce.Do(func() { close(svc.ready) })
+		return
+	}
+
+	exited := make(chan struct{})
+	svc.mu.Lock()
+	svc.generation++
+	generation := svc.generation
+	svc.cmd = cmd
+	svc.exited = exited
+	svc.mu.Unlock()
+
+	s.log(svc, fmt.Sprintf("started (pid %d)", cmd.Process.Pid))
+	go s.awaitReady(svc, generation)
+
+	err := cmd.Wait()
+	close(exited)
+	svc.output.Close()
+
+	svc.mu.Lock()
+	current := svc.generation == generation
+	if current {
+		svc.cmd = nil
+	}
+	svc.mu.Unlock()
+
+	// Stopped intentionally (restart/shutdown)
+	if !current || s.isClosing() {
+		return
+	}
+
+	status := "exited"
+	if err != nil {
+		status = fmt.Sprintf("crashed (%v)", err)
+	}
+
+	if svc.Restart == "never" || (svc.Restart == "on-failure" && err == nil) {
+		s.log(svc, status)
+		svc.readyonce.Do(func() { close(svc.ready) })
+		if svc.proxy != nil {
+			svc.proxy.Resume(false)
+		}
+		return
+	}
+
+	// Requests are held until the service is restarted
+	if svc.proxy != nil {
+		svc.proxy.Pause()
+	}
+
+	var wait time.Duration
+	wait, svc.backoff = restartDelay(svc.backoff, time.Since(started))
+
+	s.log(svc, fmt.Sprintf("%s, restarting in %s", status, wait))
+	time.Sleep(wait)
+
+	// A change may have restarted the service while waiting
+	svc.mu.Lock()
+	restarted := svc.generation != generation
+	svc.mu.Unlock()
+
+	if !restarted {
+		s.start(svc)
+	}
+}
+
+// restartDelay returns the delay before restarting a process that ran for
+// the uptime, and the (doubled) backoff of the next restart. A process that
+// ran for at least stableuptime resets the backoff.
+func restartDelay(backoff, uptime time.Duration) (time.Duration, time.Duration) {
+	if uptime >= stableuptime {
+		backoff = minbackoff
+	}
+
+	next := backoff * 2
+	if next > maxbackoff {
+		next = maxbackoff
+	}
+
+	return backoff, next
+}
+
+// awaitReady runs the readiness check of a service (if any). Services that
+// depend on it are started once it is ready.
+func (s *supervisor) awaitReady(svc *devService, generation int) {
+	if svc.ReadyPort == 0 && len(svc.ReadyHTTP) == 0 {
+		svc.readyonce.Do(func() { close(svc.ready) })
+		return
+	}
+
+	deadline := time.Now().Add(svc.ReadyTimeout)
+	client := &http.Client{Timeout: time.Second}
+	for time.Now().Before(deadline) {
+		svc.mu.Lock()
+		stale := svc.generation != generation || svc.cmd == nil
+		svc.mu.Unlock()
+		if stale || s.isClosing() {
+			return
+		}
+
+		ready := true
+		if svc.ReadyPort > 0 {
+			conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", svc.ReadyPort), time.Second)
+			if err != nil {
+				ready = false
+			} else {
+				conn.Close()
+			}
+		}
+
+		if ready && len(svc.ReadyHTTP) > 0 {
+			res, err := client.Get(svc.ReadyHTTP)
+			if err != nil {
+				ready = false
+			} else {
+				res.Body.Close()
+				ready = res.StatusCode < 400
+			}
+		}
+
+		if ready {
+			s.log(svc, "ready")
+			svc.readyonce.Do(func() { close(svc.ready) })
+			if svc.proxy != nil {
+				svc.proxy.Resume(true)
+			}
+			return
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+
+	s.log(svc, fmt.Sprintf("not ready after %s (starting dependent services anyway)", svc.ReadyTimeout))
+	svc.readyonce.Do(func() { close(svc.ready) })
+	if svc.proxy != nil {
+		svc.proxy.Resume(false)
+	}
+}
+
+// stop stops the process of a service (forcibly, if it does not stop in time).
+func (s *supervisor) stop(svc *devService) {
+	svc.mu.Lock()
+	cmd, exited := svc.cmd, svc.exited
+	svc.generation++
+	svc.cmd = nil
+	svc.mu.Unlock()
+
+	if cmd == nil {
+		return
+	}
+
+	interrupt(cmd)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		terminate(cmd)
+	}
+}
+
+// watch rebuilds and restarts a service when watched files change.
+func (s *supervisor) watch(svc *devService) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		s.log(svc, fmt.Sprintf("live reload unavailable: %v", err))
+		return
+	}
+	defer watcher.Close()
+
+	wd, _ := os.Getwd()
+	output, _ := filepath.Abs(filepath.Dir(svc.binary))
+
+	filepath.Walk(wd, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+
+		name := info.Name()
+		if path != wd && (strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" || path == output) {
+			return filepath.SkipDir
+		}
+
+		watcher.Add(path)
+		return nil
+	})
+
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			rel, err := filepath.Rel(wd, event.Name)
+			if err != nil || !watched(svc.Watch, filepath.ToSlash(rel)) {
+				continue
+			}
+
+			// Editors often write several events per save
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(300*time.Millisecond, func() {
+				s.reload(svc, rel)
+			})
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			s.log(svc, fmt.Sprintf("watch error: %v", err))
+		}
+	}
+}
+
+// watched determines whether a file matches any of the (glob) patterns.
+// A leading "**/" matches any directory.
+func watched(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		if match(pattern, file) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func match(pattern, file string) bool {
+	if strings.HasPrefix(pattern, "**/") {
+		rest := pattern[3:]
+		if match(rest, file) {
+			return true
+		}
+
+		parts := strings.Split(file, "/")
+		for i := 1; i < len(parts); i++ {
+			if match(rest, strings.Join(parts[i:], "/")) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	matched, _ := filepath.Match(pattern, file)
+	return matched
+}
+
+// reload rebuilds and restarts a service after a change.
+func (s *supervisor) reload(svc *devService, changed string) {
+	// Changes made while a reload is in progress wait for it to finish
+	// (the same binary is never built concurrently)
+	svc.reloadmu.Lock()
+	defer svc.reloadmu.Unlock()
+
+	if s.isClosing() {
+		return
+	}
+
+	s.log(svc, fmt.Sprintf("%s changed, restarting", changed))
+
+	if !s.build(svc) {
+		s.log(svc, "build failed (waiting for changes)")
+		return
+	}
+
+	// Requests are held until the new process is ready
+	if svc.proxy != nil {
+		svc.proxy.Pause()
+	}
+
+	s.stop(svc)
+	svc.backoff = minbackoff
+	go s.start(svc)
+}
+
+// shutdown stops all services (dependents first).
+func (s *supervisor) shutdown() {
+	s.closemu.Lock()
+	s.closing = true
+	s.closemu.Unlock()
+
+	fmt.Println("")
+	util.Stdout("# stopping services\n")
+
+	for i := len(s.services) - 1; i >= 0; i-- {
+		svc := s.services[i]
+		svc.mu.Lock()
+		running := svc.cmd != nil
+		svc.mu.Unlock()
+
+		if running {
+			s.stop(svc)
+			s.log(svc, "stopped")
+		}
+	}
+
+	os.Exit(0)
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatched(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		file     string
+		matched  bool
+	}{
+		{[]string{"*.go"}, "main.go", true},
+		{[]string{"*.go"}, "cmd/main.go", false},
+		{[]string{"**/*.go"}, "main.go", true},
+		{[]string{"**/*.go"}, "cmd/app/main.go", true},
+		{[]string{"**/*.go"}, "main.js", false},
+		{[]string{"./config/*.json"}, "config/app.json", true},
+		{[]string{"config/*.json"}, "config/nested/app.json", false},
+		{[]string{"config/**/*.json"}, "config/nested/app.json", true},
+		{[]string{"*.css", "**/*.go"}, "web/server.go", true},
+		{[]string{"public/**"}, "public/index.html", true},
+		{[]string{"public/**"}, "public/css/site.css", false},
+		{[]string{"**/public/*"}, "web/public/index.html", true},
+		{[]string{}, "main.go", false},
+	}
+
+	for _, test := range tests {
+		if matched := watched(test.patterns, test.file); matched != test.matched {
+			t.Errorf("watched(%q, %q) = %v, expected %v", test.patterns, test.file, matched, test.matched)
+		}
+	}
+}
+
+func TestRestartDelay(t *testing.T) {
+	tests := []struct {
+		backoff time.Duration
+		uptime  time.Duration
+		wait    time.Duration
+		next    time.Duration
+	}{
+		{minbackoff, 0, minbackoff, 2 * minbackoff},
+		{2 * time.Second, time.Second, 2 * time.Second, 4 * time.Second},
+		{16 * time.Second, time.Second, 16 * time.Second, maxbackoff},
+		{maxbackoff, time.Second, maxbackoff, maxbackoff},
+		{maxbackoff, stableuptime, minbackoff, 2 * minbackoff},
+		{8 * time.Second, time.Minute, minbackoff, 2 * minbackoff},
+	}
+
+	for _, test := range tests {
+		wait, next := restartDelay(test.backoff, test.uptime)
+		if wait != test.wait || next != test.next {
+			t.Errorf("restartDelay(%s, %s) = %s, %s, expected %s, %s", test.backoff, test.uptime, wait, next, test.wait, test.next)
+		}
+	}
+}
+
+func TestCommandEnvironment(t *testing.T) {
+	t.Setenv("QGO_MANIFEST", "/project/config/manifest.json")
+
+	// The app run by "qgo run" (proxied) and a WASM app served by qgo
+	app := newDevService("app")
+	app.App = true
+	app.binary = "/project/bin/app"
+	app.Env["PORT"] = "8080"
+
+	web := newDevService("web")
+	web.Target = "web"
+	web.wasm = true
+
+	s := &supervisor{services: []*devService{app, web}, exe: "/usr/local/bin/qgo"}
+
+	for _, svc := range s.services {
+		cmd := s.command(svc)
+
+		env := map[string]string{}
+		for _, item := range cmd.Env {
+			if key, value, found := strings.Cut(item, "="); found {
+				env[key] = value
+			}
+		}
+
+		if manifest := env["QGO_MANIFEST"]; manifest != "/project/config/manifest.json" {
+			t.Errorf("%s: QGO_MANIFEST = %q, expected the explicit manifest", svc.Name, manifest)
+		}
+
+		for key, value := range svc.Env {
+			if env[key] != value {
+				t.Errorf("%s: %s = %q, expected %q", svc.Name, key, env[key], value)
+			}
+		}
+	}
+}
@@ -0,0 +1,484 @@
+package commands
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/fsnotify/fsnotify"
+	"github.com/quikdev/go/util"
+)
+
+// Files worth compressing (web assemblies compress especially well)
+var compressible = []string{".wasm", ".js", ".mjs", ".html", ".css", ".json", ".svg", ".map", ".txt", ".xml"}
+
+func init() {
+	// Browsers require this type to stream-compile web assemblies
+	mime.AddExtensionType(".wasm", "application/wasm")
+	mime.AddExtensionType(".mjs", "text/javascript")
+}
+
+// devServer serves a web assembly and its assets during development, with
+// live reload, compression, optional HTTPS, a single page app fallback, and
+// proxies to backend services.
+type devServer struct {
+	Root     string
+	Host     string
+	Port     int
+	HTTPS    bool
+	Fallback string            // Served for unknown paths (single page apps)
+	Proxy    map[string]string // Path prefix (ex: /api) → backend URL
+
+	reloader *livereload
+
+	mu    sync.Mutex
+	cache map[string]*compressed // Compressed files, by encoding and path
+}
+
+type compressed struct {
+	modified time.Time
+	content  []byte
+}
+
+func newDevServer(root string, reloader *livereload) *devServer {
+	return &devServer{
+		Root:     root,
+		Proxy:    map[string]string{},
+		reloader: reloader,
+		cache:    map[string]*compressed{},
+	}
+}
+
+// configure applies the "server" manifest attribute:
+//
+//	"server": {
+//	  "host": "0.0.0.0",
+//	  "https": true,
+//	  "spa": true,                            // or the fallback file (ex: "app.html")
+//	  "proxy": {"/api": "http://localhost:8080"}
+//	}
+func (s *devServer) configure(value interface{}) error {
+	cfg, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf(`invalid "server" value (expected an object)`)
+	}
+
+	if host, ok := cfg["host"].(string); ok {
+		s.Host = host
+	}
+
+	if https, ok := cfg["https"].(bool); ok {
+		s.HTTPS = https
+	}
+
+	switch spa := cfg["spa"].(type) {
+	case bool:
+		if spa {
+			s.Fallback = "index.html"
+		}
+	case string:
+		s.Fallback = spa
+	}
+
+	if proxies, ok := cfg["proxy"].(map[string]interface{}); ok {
+		for prefix, backend := range proxies {
+			target, ok := backend.(string)
+			if !ok {
+				return fmt.Errorf(`invalid server proxy for "%s" (expected a URL)`, prefix)
+			}
+			s.Proxy[prefix] = target
+		}
+	}
+
+	return nil
+}
+
+// URL returns the address of the server.
+func (s *devServer) URL() string {
+	scheme := "http"
+	if s.HTTPS {
+		scheme = "https"
+	}
+
+	host := s.Host
+	if len(host) == 0 || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, fmt.Sprintf("%d", s.Port)))
+}
+
+// Handler creates the request router of the server.
+func (s *devServer) Handler() (http.Handler, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/livereload", s.reloader)
+
+	prefixes := make([]string, 0, len(s.Proxy))
+	for prefix := range s.Proxy {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	for _, prefix := range prefixes {
+		target, err := url.Parse(s.Proxy[prefix])
+		if err != nil || len(target.Host) == 0 {
+			return nil, fmt.Errorf(`invalid server proxy URL "%s" for %s`, s.Proxy[prefix], prefix)
+		}
+
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.FlushInterval = -1
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, fmt.Sprintf("%s is unavailable (%v)", target, err), http.StatusBadGateway)
+		}
+
+		prefix = "/" + strings.Trim(prefix, "/")
+		mux.Handle(prefix, proxy)
+		mux.Handle(prefix+"/", proxy)
+	}
+
+	mux.HandleFunc("/", s.serveFile)
+
+	return mux, nil
+}
+
+// ListenAndServe starts the server (blocking).
+func (s *devServer) ListenAndServe() error {
+	handler, err := s.Handler()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              net.JoinHostPort(s.Host, fmt.Sprintf("%d", s.Port)),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if !s.HTTPS {
+		return server.ListenAndServe()
+	}
+
+	cert, err := devCertificate(s.Host)
+	if err != nil {
+		return fmt.Errorf("cannot create a certificate for HTTPS: %v", err)
+	}
+	server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	return server.ListenAndServeTLS("", "")
+}
+
+func (s *devServer) serveFile(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(name, "/") {
+		name += "index.html"
+	}
+
+	file := filepath.Join(s.Root, filepath.FromSlash(name))
+	info, err := os.Stat(file)
+	if err == nil && info.IsDir() {
+		file = filepath.Join(file, "index.html")
+		info, err = os.Stat(file)
+	}
+
+	// Client-side routes (paths without a file extension) use the fallback
+	if err != nil && len(s.Fallback) > 0 && len(path.Ext(name)) == 0 {
+		file = filepath.Join(s.Root, filepath.FromSlash(s.Fallback))
+		info, err = os.Stat(file)
+	}
+
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ext := strings.ToLower(filepath.Ext(file))
+	if contentType := mime.TypeByExtension(ext); len(contentType) > 0 {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+
+	// Pages are served with the live reload script
+	if ext == ".html" {
+		s.servePage(w, r, file, info)
+		return
+	}
+
+	if util.InSlice[string](ext, compressible) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		accepted := r.Header.Get("Accept-Encoding")
+		for _, encoding := range []string{"br", "gzip"} {
+			if !strings.Contains(accepted, encoding) {
+				continue
+			}
+
+			content, err := s.compress(file, info, encoding)
+			if err != nil {
+				break
+			}
+
+			w.Header().Set("Content-Encoding", encoding)
+			http.ServeContent(w, r, file, info.ModTime(), bytes.NewReader(content))
+			return
+		}
+	}
+
+	http.ServeFile(w, r, file)
+}
+
+// servePage serves an HTML page with the live reload script.
+func (s *devServer) servePage(w http.ResponseWriter, r *http.Request, file string, info os.FileInfo) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	content = injectScript(content)
+
+	w.Header().Add("Vary", "Accept-Encoding")
+	accepted := r.Header.Get("Accept-Encoding")
+	for _, encoding := range []string{"br", "gzip"} {
+		if strings.Contains(accepted, encoding) {
+			if encoded, err := encode(content, encoding); err == nil {
+				w.Header().Set("Content-Encoding", encoding)
+				content = encoded
+			}
+			break
+		}
+	}
+
+	http.ServeContent(w, r, file, info.ModTime(), bytes.NewReader(content))
+}
+
+// watch notifies connected browsers when assets in the root directory change.
+// Stylesheets are swapped without a reload. Web assemblies are ignored, since
+// browsers are reloaded after each rebuild.
+func (s *devServer) watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		util.Stderr(err)
+		return
+	}
+	defer watcher.Close()
+
+	filepath.Walk(s.Root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			watcher.Add(path)
+		}
+		return nil
+	})
+
+	var timer *time.Timer
+	css := map[string]bool{}
+	reload := false
+	var mu sync.Mutex
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			// Build output (including the umask probe of go build) is reloaded
+			// by the rebuild itself
+			ext := strings.ToLower(filepath.Ext(event.Name))
+			if ext == ".wasm" || ext == ".br" || ext == ".gz" || strings.HasSuffix(event.Name, "~") || strings.Contains(event.Name, ".wasm-go-tmp") {
+				continue
+			}
+
+			rel, err := filepath.Rel(s.Root, event.Name)
+			if err != nil {
+				continue
+			}
+
+			// Editors often write several events per save
+			mu.Lock()
+			if ext == ".css" {
+				css["/"+filepath.ToSlash(rel)] = true
+			} else {
+				reload = true
+			}
+			mu.Unlock()
+
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(150*time.Millisecond, func() {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if reload {
+					s.reloader.Reload()
+				} else {
+					for path := range css {
+						s.reloader.CSS(path)
+					}
+				}
+
+				css = map[string]bool{}
+				reload = false
+			})
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			util.Stderr(err)
+		}
+	}
+}
+
+// compress returns the compressed content of a file. Precompressed files
+// (ex: app.wasm.br) are used when they are up to date. Otherwise, the file is
+// compressed once and cached until it changes.
+func (s *devServer) compress(file string, info os.FileInfo, encoding string) ([]byte, error) {
+	extension := ".gz"
+	if encoding == "br" {
+		extension = ".br"
+	}
+
+	if precompressed, err := os.Stat(file + extension); err == nil && !precompressed.ModTime().Before(info.ModTime()) {
+		return os.ReadFile(file + extension)
+	}
+
+	key := encoding + ":" + file
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, exists := s.cache[key]; exists && cached.modified.Equal(info.ModTime()) {
+		return cached.content, nil
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err = encode(content, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache[key] = &compressed{modified: info.ModTime(), content: content}
+
+	return content, nil
+}
+
+// encode compresses content with brotli (br) or gzip.
+func encode(content []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if encoding == "br" {
+		writer := brotli.NewWriterLevel(&buf, brotli.DefaultCompression)
+		writer.Write(content)
+		err = writer.Close()
+	} else {
+		writer := gzip.NewWriter(&buf)
+		writer.Write(content)
+		err = writer.Close()
+	}
+
+	return buf.Bytes(), err
+}
+
+// devCertificate returns a self-signed certificate for localhost (and the
+// host, if any). The certificate is generated once and stored in the user
+// cache directory, so browsers only need to trust it once.
+func devCertificate(host string) (tls.Certificate, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+	dir := filepath.Join(cache, "qgo", "certs")
+	certfile := filepath.Join(dir, "localhost.pem")
+	keyfile := filepath.Join(dir, "localhost-key.pem")
+
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if len(host) > 0 && host != "0.0.0.0" && host != "::" && !util.InSlice[string](host, hosts) {
+		hosts = append(hosts, host)
+	}
+
+	if cert, err := tls.LoadX509KeyPair(certfile, keyfile); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil && time.Now().Add(24*time.Hour).Before(leaf.NotAfter) {
+			valid := true
+			for _, h := range hosts {
+				if leaf.VerifyHostname(h) != nil {
+					valid = false
+				}
+			}
+			if valid {
+				return cert, nil
+			}
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"QuikGo development server"}, CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	certpem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keypem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyder})
+
+	if err := os.MkdirAll(dir, 0700); err == nil {
+		os.WriteFile(certfile, certpem, 0644)
+		os.WriteFile(keyfile, keypem, 0600)
+		util.SubtleHighlight("created a self-signed certificate (" + certfile + ")")
+	}
+
+	return tls.X509KeyPair(certpem, keypem)
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/quikdev/go/context"
+	"github.com/quikdev/go/util"
+)
+
+type Env struct {
+	Profile []string `name:"profile" optional:"" help:"Name of the manifest.json profile attribute to apply."`
+}
+
+func (e *Env) Run(c *Context) error {
+	ctx := context.New(e.Profile...)
+	ctx.Configure()
+
+	vars, err := ctx.GetConfig().Environment()
+	util.BailOnError(err)
+
+	if len(vars) == 0 {
+		util.Stdout("no environment variables configured (see the \"env\" and \"env_files\" manifest attributes)\n")
+		return nil
+	}
+
+	// Multiline values are displayed on a single line
+	values := make([]string, len(vars))
+	width := 0
+	for i, v := range vars {
+		values[i] = strings.ReplaceAll(v.Masked(), "\n", `\n`)
+		if len(v.Name)+len(values[i])+1 > width {
+			width = len(v.Name) + len(values[i]) + 1
+		}
+	}
+
+	name := color.New(color.FgYellow, color.Faint).SprintFunc()
+	dim := color.New(color.Faint).SprintFunc()
+	for i, v := range vars {
+		pad := width - len(v.Name) - len(values[i]) - 1
+		fmt.Printf("%s%s%s%*s  %s\n", name(v.Name), dim("="), values[i], pad, "", dim("# "+v.Source))
+	}
+
+	return nil
+}
@@ -22,13 +22,20 @@ func (d *Do) Run(c *Context) error {
 
 	ctx.Configure()
 
-	scripts, exists := ctx.GetConfig().Get("scripts")
+	// Only the script being run is interpolated (other scripts may reference
+	// values that are not available)
+	scripts, exists := ctx.GetConfig().GetRaw("scripts")
 	if !exists {
 		util.Stderr("no scripts defined", true)
 	}
 
 	if cmd, exists := scripts.(map[string]interface{})[d.Script]; exists {
-		command := []string{cmd.(string)}
+		script, err := ctx.GetConfig().Interpolate(cmd.(string))
+		if err != nil {
+			util.Stderr(fmt.Sprintf("%v (in \"scripts.%s\")\n", err, d.Script), true)
+		}
+
+		command := []string{script}
 		if len(d.Args) > 0 {
 			// command = append(command, "--")
 			command = append(command, d.Args...)
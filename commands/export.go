@@ -0,0 +1,395 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/quikdev/go/context"
+	"github.com/quikdev/go/util"
+
+	fs "github.com/coreybutler/go-fsutil"
+)
+
+// The export manifest maps the original asset paths to the fingerprinted ones.
+// It also identifies directories that can be overwritten by the next export.
+const exportManifest = "asset-manifest.json"
+
+// Files that are requested by well-known names, so they are never fingerprinted
+var unhashed = map[string]bool{
+	"favicon.ico":          true,
+	"robots.txt":           true,
+	"sitemap.xml":          true,
+	"manifest.json":        true,
+	"manifest.webmanifest": true,
+	"sw.js":                true,
+	"service-worker.js":    true,
+	"_headers":             true,
+	"_redirects":           true,
+	"CNAME":                true,
+}
+
+var (
+	// The live reload script injected by the dev server
+	injectedReload = regexp.MustCompile(`(?s)<script>\s*\(function \(\) \{\s*if \(window\.__qgoLivereload\).*?</script>`)
+	// The live reload snippet of pages generated by earlier versions of qgo init
+	templateReload = regexp.MustCompile(`(?s)\s*const url = new URL\(location\.href\);\s*url\.pathname = "/livereload";.*?events\.onerror = function\(err\) \{.*?\n\s*\}\n`)
+)
+
+// exportSite assembles a deployable static site from the files served by the
+// dev server (the build output directory) and the public directory (which
+// takes precedence). Asset file names are fingerprinted with a content hash,
+// references in HTML pages, stylesheets, and scripts are rewritten, and live
+// reload code is removed.
+//
+// The public directory is configured with the "export" manifest attribute:
+//
+//	"export": {
+//	  "public": "web/public",  // Static assets (defaults to "public")
+//	  "fingerprint": true      // Add content hashes to asset file names (default)
+//	}
+func exportSite(ctx *context.Context, dir string) error {
+	public := "public"
+	fingerprint := true
+	if value, exists := ctx.GetConfig().Get("export"); exists {
+		settings, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf(`invalid "export" value (expected an object)`)
+		}
+		if p, ok := settings["public"].(string); ok {
+			public = p
+		}
+		if f, ok := settings["fingerprint"].(bool); ok {
+			fingerprint = f
+		}
+	}
+
+	// Relative paths are relative to the project
+	if !filepath.IsAbs(public) {
+		public = filepath.Join(ctx.CWD, public)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(ctx.CWD, dir)
+	}
+
+	output, _ := filepath.Abs(filepath.Dir(ctx.Output()))
+	public, _ = filepath.Abs(public)
+	dest, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := prepareExport(dest, output, public); err != nil {
+		return err
+	}
+
+	// Collect the files (relative slash paths) and their source
+	files := map[string]string{}
+	for _, root := range []string{output, public} {
+		if !fs.IsDirectory(root) {
+			continue
+		}
+
+		err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+
+			// Precompressed files are exported along with their source, and
+			// SBOM/provenance documents are not part of the site
+			ext := strings.ToLower(filepath.Ext(file))
+			if ext == ".br" || ext == ".gz" || strings.HasSuffix(file, "~") || strings.HasSuffix(file, ".cdx.json") || strings.HasSuffix(file, ".spdx.json") || strings.HasSuffix(file, ".provenance.json") {
+				return nil
+			}
+
+			rel, _ := filepath.Rel(root, file)
+			files[filepath.ToSlash(rel)] = file
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("nothing to export (%s is empty)", output)
+	}
+
+	// Fingerprint assets. Stylesheets and scripts reference other assets, so
+	// they are hashed after the assets they reference (and their references
+	// are rewritten).
+	names := map[string]string{}
+	pending := map[string]bool{}
+	for rel, source := range files {
+		names[rel] = rel
+		if !fingerprint || isHTML(rel) || unhashed[path.Base(rel)] || strings.HasPrefix(rel, ".well-known/") {
+			continue
+		}
+		if isRewritable(rel) {
+			pending[rel] = true
+			continue
+		}
+
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		names[rel] = hashedName(rel, contentHash(content))
+	}
+
+	rewritten := map[string][]byte{}
+	for len(pending) > 0 {
+		resolved := []string{}
+		for rel := range pending {
+			content, err := os.ReadFile(files[rel])
+			if err != nil {
+				return err
+			}
+
+			waiting := false
+			for other := range pending {
+				if other != rel && references(content, rel, other) {
+					waiting = true
+					break
+				}
+			}
+			if !waiting {
+				content = rewriteReferences(content, rel, renamed(names), names)
+				rewritten[rel] = content
+				resolved = append(resolved, rel)
+			}
+		}
+
+		// Files referencing each other (circularly) keep their names
+		if len(resolved) == 0 {
+			break
+		}
+
+		for _, rel := range resolved {
+			names[rel] = hashedName(rel, contentHash(rewritten[rel]))
+			delete(pending, rel)
+		}
+	}
+
+	assets := renamed(names)
+
+	keys := make([]string, 0, len(files))
+	for rel := range files {
+		keys = append(keys, rel)
+	}
+	sort.Strings(keys)
+
+	for _, rel := range keys {
+		source := files[rel]
+		target := filepath.Join(dest, filepath.FromSlash(names[rel]))
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+
+		original, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+
+		content := original
+		switch {
+		case isHTML(rel):
+			content = rewriteReferences(stripLivereload(content), rel, assets, names)
+		case rewritten[rel] != nil:
+			content = rewritten[rel]
+		case isRewritable(rel):
+			content = rewriteReferences(content, rel, assets, names)
+		}
+
+		// Precompressed copies are only exported when they are up to date
+		// (and the references of the file were not rewritten)
+		if !isHTML(rel) && bytes.Equal(original, content) {
+			info, _ := os.Stat(source)
+			for _, ext := range []string{".br", ".gz"} {
+				if compressed, err := os.Stat(source + ext); err == nil && !compressed.ModTime().Before(info.ModTime()) {
+					if err := fs.Copy(source+ext, target+ext); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return err
+		}
+
+		if names[rel] != rel {
+			util.Stdout(fmt.Sprintf("  ↳ %s → %s\n", rel, names[rel]))
+		} else {
+			util.Stdout(fmt.Sprintf("  ↳ %s\n", rel))
+		}
+	}
+
+	mapping, _ := json.MarshalIndent(names, "", "  ")
+	if err := os.WriteFile(filepath.Join(dest, exportManifest), mapping, 0644); err != nil {
+		return err
+	}
+
+	util.Highlight(fmt.Sprintf("exported %d files to %s", len(files), dest))
+
+	return nil
+}
+
+// prepareExport empties the export directory. Directories that were not
+// created by an export must be empty, so source files are never deleted.
+func prepareExport(dest string, protected ...string) error {
+	wd, _ := os.Getwd()
+	for _, dir := range append(protected, wd) {
+		if dest == dir || strings.HasPrefix(dir+string(filepath.Separator), dest+string(filepath.Separator)) {
+			return fmt.Errorf("cannot export to %s (it contains the project or its assets)", dest)
+		}
+	}
+
+	entries, err := os.ReadDir(dest)
+	if os.IsNotExist(err) {
+		return os.MkdirAll(dest, os.ModePerm)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(entries) > 0 && !util.FileExists(filepath.Join(dest, exportManifest)) {
+		return fmt.Errorf("cannot export to %s (the directory is not empty)", dest)
+	}
+
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dest, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hashedName adds a hash to a file name, before the extension:
+// app.wasm → app.1a2b3c4d.wasm
+func hashedName(rel string, hash string) string {
+	ext := path.Ext(rel)
+	return strings.TrimSuffix(rel, ext) + "." + hash + ext
+}
+
+func isHTML(rel string) bool {
+	ext := strings.ToLower(path.Ext(rel))
+	return ext == ".html" || ext == ".htm"
+}
+
+// stripLivereload removes live reload code from an HTML page.
+func stripLivereload(page []byte) []byte {
+	page = injectedReload.ReplaceAll(page, []byte{})
+	return templateReload.ReplaceAll(page, []byte("\n"))
+}
+
+// isRewritable determines whether a file (other than an HTML page) may
+// reference other assets.
+func isRewritable(rel string) bool {
+	ext := strings.ToLower(path.Ext(rel))
+	return ext == ".css" || ext == ".js" || ext == ".mjs"
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// renamed returns the fingerprinted assets. Longer paths come first, so
+// "app.js" does not match "vendor/app.js" when references are rewritten.
+func renamed(names map[string]string) []string {
+	assets := make([]string, 0, len(names))
+	for rel := range names {
+		if names[rel] != rel {
+			assets = append(assets, rel)
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if len(assets[i]) == len(assets[j]) {
+			return assets[i] < assets[j]
+		}
+		return len(assets[i]) > len(assets[j])
+	})
+
+	return assets
+}
+
+// referencePatterns returns the patterns matching the quoted (or url(...))
+// references to an asset in a file, mapped to the replacement path. HTML
+// pages and stylesheets reference assets relative to themselves. Scripts
+// may also reference assets relative to the site root (ex: fetch() calls
+// are relative to the page).
+func referencePatterns(rel string, asset string, hashed string) map[*regexp.Regexp]string {
+	bases := []string{path.Dir(rel)}
+	if ext := strings.ToLower(path.Ext(rel)); (ext == ".js" || ext == ".mjs") && bases[0] != "." {
+		bases = append(bases, ".")
+	}
+
+	forms := map[string]string{"/" + asset: "/" + hashed}
+	for _, base := range bases {
+		if relative, ok := relativePath(base, asset); ok {
+			forms[relative] = strings.TrimSuffix(relative, path.Base(asset)) + path.Base(hashed)
+			forms["./"+relative] = "./" + forms[relative]
+		}
+	}
+
+	patterns := map[*regexp.Regexp]string{}
+	for original, replacement := range forms {
+		pattern := regexp.MustCompile(`(["'(=])` + regexp.QuoteMeta(original) + `([?#"')])`)
+		patterns[pattern] = replacement
+	}
+
+	return patterns
+}
+
+// references determines whether a file references an asset.
+func references(content []byte, rel string, asset string) bool {
+	for pattern := range referencePatterns(rel, asset, asset) {
+		if pattern.Match(content) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rewriteReferences replaces the references (relative or absolute) to
+// fingerprinted assets in an HTML page, stylesheet, or script.
+func rewriteReferences(page []byte, rel string, assets []string, names map[string]string) []byte {
+	content := string(page)
+
+	for _, asset := range assets {
+		for pattern, hashed := range referencePatterns(rel, asset, names[asset]) {
+			content = pattern.ReplaceAllString(content, "${1}"+strings.ReplaceAll(hashed, "$", "$$")+"${2}")
+		}
+	}
+
+	return []byte(content)
+}
+
+// relativePath returns the path of an asset relative to the directory of a
+// page (both slash paths relative to the site root).
+func relativePath(base string, asset string) (string, bool) {
+	if base == "." {
+		return asset, true
+	}
+
+	rel, err := filepath.Rel(filepath.FromSlash(base), filepath.FromSlash(asset))
+	if err != nil {
+		return "", false
+	}
+
+	return filepath.ToSlash(rel), true
+}
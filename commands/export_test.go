@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashedName(t *testing.T) {
+	tests := []struct {
+		rel      string
+		expected string
+	}{
+		{"app.js", "app.0123abcd.js"},
+		{"css/site.css", "css/site.0123abcd.css"},
+		{"js/vendor.min.js", "js/vendor.min.0123abcd.js"},
+		{"LICENSE", "LICENSE.0123abcd"},
+	}
+
+	for _, test := range tests {
+		if name := hashedName(test.rel, "0123abcd"); name != test.expected {
+			t.Errorf("hashedName(%q) = %q, expected %q", test.rel, name, test.expected)
+		}
+	}
+}
+
+func TestIsRewritable(t *testing.T) {
+	tests := map[string]bool{
+		"css/site.css":   true,
+		"js/app.js":      true,
+		"js/module.MJS":  true,
+		"index.html":     false,
+		"img/logo.png":   false,
+		"app.wasm":       false,
+		"data/site.json": false,
+	}
+
+	for rel, expected := range tests {
+		if rewritable := isRewritable(rel); rewritable != expected {
+			t.Errorf("isRewritable(%q) = %v, expected %v", rel, rewritable, expected)
+		}
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	tests := []struct {
+		base     string
+		asset    string
+		expected string
+	}{
+		{".", "css/site.css", "css/site.css"},
+		{"css", "css/site.css", "site.css"},
+		{"css", "img/logo.png", "../img/logo.png"},
+		{"docs/guide", "js/app.js", "../../js/app.js"},
+	}
+
+	for _, test := range tests {
+		if rel, ok := relativePath(test.base, test.asset); !ok || rel != test.expected {
+			t.Errorf("relativePath(%q, %q) = %q, %v, expected %q", test.base, test.asset, rel, ok, test.expected)
+		}
+	}
+}
+
+func TestRenamed(t *testing.T) {
+	names := map[string]string{
+		"app.js":        "app.11111111.js",
+		"vendor/app.js": "vendor/app.22222222.js",
+		"css/site.css":  "css/site.33333333.css",
+		"favicon.ico":   "favicon.ico",
+	}
+
+	expected := []string{"vendor/app.js", "css/site.css", "app.js"}
+	if assets := renamed(names); !reflect.DeepEqual(assets, expected) {
+		t.Errorf("renamed() = %v, expected %v", assets, expected)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		content  string
+		rel      string
+		asset    string
+		expected bool
+	}{
+		{`<link href="css/site.css">`, "index.html", "css/site.css", true},
+		{`<link href="/css/site.css">`, "docs/index.html", "css/site.css", true},
+		{`<link href="../css/site.css">`, "docs/index.html", "css/site.css", true},
+		{`<link href="css/site.css">`, "docs/index.html", "css/site.css", false},
+		{`background: url(../img/logo.png);`, "css/site.css", "img/logo.png", true},
+		{`fetch("img/logo.png")`, "js/app.js", "img/logo.png", true},
+		{`<a href="vendor/app.js">`, "index.html", "app.js", false},
+		{`<p>css/site.css</p>`, "index.html", "css/site.css", false},
+	}
+
+	for _, test := range tests {
+		if found := references([]byte(test.content), test.rel, test.asset); found != test.expected {
+			t.Errorf("references(%q, %q, %q) = %v, expected %v", test.content, test.rel, test.asset, found, test.expected)
+		}
+	}
+}
+
+func TestRewriteReferences(t *testing.T) {
+	names := map[string]string{
+		"app.js":        "app.11111111.js",
+		"vendor/app.js": "vendor/app.22222222.js",
+		"css/site.css":  "css/site.33333333.css",
+		"img/logo.png":  "img/logo.44444444.png",
+		"favicon.ico":   "favicon.ico",
+	}
+	assets := renamed(names)
+
+	tests := []struct {
+		name     string
+		rel      string
+		content  string
+		expected string
+	}{
+		{
+			"page",
+			"index.html",
+			`<link href="css/site.css"><script src="./app.js?v=1"></script><script src="vendor/app.js"></script><link rel="icon" href="favicon.ico">`,
+			`<link href="css/site.33333333.css"><script src="./app.11111111.js?v=1"></script><script src="vendor/app.22222222.js"></script><link rel="icon" href="favicon.ico">`,
+		},
+		{
+			"nested page",
+			"docs/index.html",
+			`<link href="../css/site.css"><img src="/img/logo.png#top"><script src="app.js"></script>`,
+			`<link href="../css/site.33333333.css"><img src="/img/logo.44444444.png#top"><script src="app.js"></script>`,
+		},
+		{
+			"stylesheet",
+			"css/site.css",
+			`body { background: url(../img/logo.png) } .logo { background: url('/img/logo.png') }`,
+			`body { background: url(../img/logo.44444444.png) } .logo { background: url('/img/logo.44444444.png') }`,
+		},
+		{
+			"script",
+			"vendor/app.js",
+			`fetch("img/logo.png"); new URL("../img/logo.png", import.meta.url); import "./app.js";`,
+			`fetch("img/logo.44444444.png"); new URL("../img/logo.44444444.png", import.meta.url); import "./app.22222222.js";`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if content := string(rewriteReferences([]byte(test.content), test.rel, assets, names)); content != test.expected {
+				t.Errorf("rewriteReferences() = %s\nexpected %s", content, test.expected)
+			}
+		})
+	}
+}
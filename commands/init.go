@@ -386,26 +386,18 @@ func (i *Init) Run(c *Context) error {
 		os.MkdirAll(filepath.Join(abspath, "bin"), os.ModePerm)
 		util.WriteTextFile(filepath.Join(abspath, "bin", "index.html"), content, true)
 
-		// Get the wasm_exec.js file
-		url := "https://raw.githubusercontent.com/golang/go/master/misc/wasm/wasm_exec.js"
-		resp, err := http.Get(url)
-		msg := "The wasm_exec.js file is available at " + url
+		// Copy the wasm_exec.js file of the installed toolchain
+		source, _, err := context.WASMExec(false)
+		if err == nil {
+			var content []byte
+			content, err = os.ReadFile(source)
+			if err == nil {
+				err = os.WriteFile(filepath.Join(abspath, "bin", "wasm_exec.js"), content, 0644)
+			}
+		}
 		if err != nil {
 			util.Stderr(err)
-			util.SubtleHighlight(msg)
-		} else {
-			defer resp.Body.Close()
-			file, err := os.Create(filepath.Join(abspath, "bin", "wasm_exec.js"))
-			if err != nil {
-				util.Stderr(err)
-				util.SubtleHighlight(msg)
-			} else {
-				_, err := io.Copy(file, resp.Body)
-				if err != nil {
-					util.Stderr(err)
-					util.SubtleHighlight(msg)
-				}
-			}
+			util.SubtleHighlight("The wasm_exec.js file is copied to the output directory by qgo build.")
 		}
 	} else {
 		// generate main file
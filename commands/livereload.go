@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/quikdev/go/util"
+)
+
+// Browser script that handles the events of the /livereload endpoint
+// (injected into served and proxied HTML pages):
+//
+//   - reload: refresh the page
+//   - css: swap the changed stylesheet without a reload
+//   - error: display the compiler output in an overlay
+//   - clear: remove the overlay
+const livereloadScript = `<script>
+(function () {
+	if (window.__qgoLivereload) {
+		return;
+	}
+	window.__qgoLivereload = true;
+
+	const url = new URL(location.href);
+	url.pathname = "/livereload";
+	url.search = "";
+
+	const events = new EventSource(url.href);
+	events.onmessage = function (event) {
+		if (event.data === "reload") {
+			console.warn("reloading!");
+			location.reload();
+		}
+	};
+
+	events.addEventListener("css", function (event) {
+		const changed = JSON.parse(event.data).path;
+		document.querySelectorAll('link[rel="stylesheet"]').forEach(function (link) {
+			const href = new URL(link.href);
+			if (href.origin === location.origin && (!changed || href.pathname === changed)) {
+				href.searchParams.set("qgo", Date.now());
+				link.href = href.href;
+			}
+		});
+	});
+
+	events.addEventListener("error", function (event) {
+		// Connection errors have no data
+		if (!event.data) {
+			return;
+		}
+
+		let overlay = document.getElementById("qgo-overlay");
+		if (!overlay) {
+			overlay = document.createElement("div");
+			overlay.id = "qgo-overlay";
+			overlay.style.cssText = "position:fixed;inset:0;z-index:2147483647;overflow:auto;padding:2em;background:rgba(20,20,20,.94);color:#eee;font:14px/1.5 monospace";
+			overlay.innerHTML = '<div style="color:#ff6b6b;font-weight:bold;margin-bottom:1em">Build failed</div><pre style="white-space:pre-wrap;margin:0"></pre>';
+			overlay.onclick = function () {
+				overlay.remove();
+			};
+			document.body.appendChild(overlay);
+		}
+		overlay.querySelector("pre").textContent = JSON.parse(event.data).message;
+	});
+
+	events.addEventListener("clear", function () {
+		const overlay = document.getElementById("qgo-overlay");
+		if (overlay) {
+			overlay.remove();
+		}
+	});
+})();
+</script>`
+
+// liveEvent is an event sent to connected browsers.
+type liveEvent struct {
+	Type    string `json:"-"`                 // reload, css, error, or clear
+	Path    string `json:"path,omitempty"`    // The changed stylesheet (css)
+	Message string `json:"message,omitempty"` // The compiler output (error)
+}
+
+// format returns the event as a server-sent event. Reload events are sent as
+// unnamed "reload" messages, which pages generated by earlier versions of qgo
+// understand. Other events are named, with a JSON payload.
+func (e liveEvent) format() []byte {
+	if e.Type == "reload" {
+		return []byte("data: reload\n\n")
+	}
+
+	payload, _ := json.Marshal(e)
+	return []byte("event: " + e.Type + "\ndata: " + string(payload) + "\n\n")
+}
+
+// livereload is a server-sent events (SSE) endpoint that notifies connected
+// browsers when the app has been rebuilt.
+type livereload struct {
+	mu          sync.Mutex
+	subscribers map[chan liveEvent]bool
+}
+
+func newLivereload() *livereload {
+	return &livereload{subscribers: make(map[chan liveEvent]bool)}
+}
+
+// Send delivers an event to every connected browser.
+func (l *livereload) Send(event liveEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for subscriber := range l.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// Reload refreshes connected browsers.
+func (l *livereload) Reload() {
+	l.Send(liveEvent{Type: "reload"})
+}
+
+// CSS swaps a stylesheet (identified by its URL path) in connected browsers.
+func (l *livereload) CSS(path string) {
+	l.Send(liveEvent{Type: "css", Path: path})
+}
+
+// Error displays compiler output in connected browsers.
+func (l *livereload) Error(message string) {
+	l.Send(liveEvent{Type: "error", Message: message})
+}
+
+// Clear removes the error overlay from connected browsers.
+func (l *livereload) Clear() {
+	l.Send(liveEvent{Type: "clear"})
+}
+
+func (l *livereload) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	util.SubtleHighlight(r.Header.Get("User-Agent") + " connected")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	clientChan := make(chan liveEvent, 4)
+	l.mu.Lock()
+	l.subscribers[clientChan] = true
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		delete(l.subscribers, clientChan)
+		l.mu.Unlock()
+	}()
+	defer r.Body.Close()
+
+	for {
+		select {
+		case event := <-clientChan:
+			w.Write(event.format())
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+			util.Stdout("\n" + r.UserAgent() + " disconnected")
+			return
+		}
+	}
+}
+
+// injectScript adds the live reload script to an HTML page.
+func injectScript(page []byte) []byte {
+	result := make([]byte, 0, len(page)+len(livereloadScript))
+	if i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>")); i >= 0 {
+		result = append(result, page[:i]...)
+		result = append(result, livereloadScript...)
+		return append(result, page[i:]...)
+	}
+
+	result = append(result, page...)
+	return append(result, livereloadScript...)
+}
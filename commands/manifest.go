@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/quikdev/go/context"
+	"github.com/quikdev/go/util"
+)
+
+type Manifest struct {
+	Show ManifestShow `cmd:"show" help:"Display the manifest."`
+}
+
+type ManifestShow struct {
+	Resolved bool     `name:"resolved" short:"r" type:"bool" help:"Display the effective manifest (inherited manifests and profiles applied), identifying where each value came from."`
+	Profile  []string `name:"profile" optional:"" help:"Name of the manifest.json profile attribute to apply."`
+}
+
+func (m *ManifestShow) Run(c *Context) error {
+	ctx := context.New(m.Profile...)
+	cfg := ctx.GetConfig()
+
+	if !cfg.ManifestExists() {
+		util.Stderr("manifest not found", true)
+	}
+
+	if m.Resolved {
+		fmt.Println(cfg.Resolved())
+		return nil
+	}
+
+	raw, err := cfg.Raw()
+	util.BailOnError(err)
+	fmt.Println(string(raw))
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// Colors used to distinguish the output of each module/service
+var prefixColors = []color.Attribute{color.FgCyan, color.FgMagenta, color.FgYellow, color.FgGreen, color.FgBlue, color.FgHiCyan, color.FgHiMagenta, color.FgHiYellow}
+
+// prefixWriter writes each line of output with a prefix. Writers sharing
+// a mutex never interleave partial lines. A writer is safe for concurrent
+// use (ex: by the stdout and stderr of a process).
+type prefixWriter struct {
+	prefix string
+	mu     *sync.Mutex // Guards the buffer and serializes output
+	buffer []byte
+}
+
+func newPrefixWriter(prefix string, mu *sync.Mutex) *prefixWriter {
+	return &prefixWriter{prefix: prefix, mu: mu}
+}
+
+// prefixFor returns a colored, padded prefix (ex: "api   |").
+func prefixFor(label string, width int, index int) string {
+	return color.New(prefixColors[index%len(prefixColors)]).Sprintf("%-*s |", width, label)
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buffer = append(w.buffer, p...)
+	for {
+		i := bytes.IndexByte(w.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.println(string(bytes.TrimRight(w.buffer[:i], "\r")))
+		w.buffer = w.buffer[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Close writes any remaining (unterminated) output.
+func (w *prefixWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buffer) > 0 {
+		w.println(string(w.buffer))
+		w.buffer = nil
+	}
+
+	return nil
+}
+
+// println writes a line (the caller holds the lock).
+func (w *prefixWriter) println(line string) {
+	fmt.Printf("%s %s\n", w.prefix, line)
+}
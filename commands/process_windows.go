@@ -0,0 +1,21 @@
+//go:build windows
+
+package commands
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// isolate is a no-op on Windows, where taskkill stops the process tree.
+func isolate(cmd *exec.Cmd) {}
+
+// interrupt asks the process (tree) to stop.
+func interrupt(cmd *exec.Cmd) error {
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
+
+// terminate forcibly stops the process (tree).
+func terminate(cmd *exec.Cmd) error {
+	return exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// devProxy is a reverse proxy in front of an app, which listens on the public
+// port and forwards requests to the app on an internal port. Requests are held
+// while the app restarts, so clients never see a refused connection. HTML
+// pages are served with a live reload script, which refreshes them when the
+// app restarts.
+type devProxy struct {
+	Port     int           // The public port
+	Upstream int           // The internal port of the app
+	Env      string        // The environment variable providing the internal port to the app
+	Timeout  time.Duration // How long requests are held while the app is unavailable
+
+	reloader *livereload
+	proxy    *httputil.ReverseProxy
+
+	mu     sync.Mutex
+	gate   chan struct{} // Closed while the app is available
+	paused bool
+}
+
+// parseProxy reads the proxy configuration, which is a port or an object:
+//
+//	"proxy": 8080
+//	"proxy": {"port": 8080, "env": "PORT", "timeout": "30s"}
+func parseProxy(value interface{}) (*devProxy, error) {
+	proxy := &devProxy{Env: "PORT", Timeout: 30 * time.Second}
+
+	switch v := value.(type) {
+	case float64:
+		proxy.Port = int(v)
+	case map[string]interface{}:
+		if port, ok := v["port"].(float64); ok {
+			proxy.Port = int(port)
+		}
+		if env, ok := v["env"].(string); ok && len(env) > 0 {
+			proxy.Env = env
+		}
+		if timeout, ok := v["timeout"].(string); ok {
+			duration, err := time.ParseDuration(timeout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy timeout: %v", err)
+			}
+			proxy.Timeout = duration
+		}
+	case bool:
+		if !v {
+			return nil, nil
+		}
+	default:
+		return nil, fmt.Errorf(`invalid "proxy" value (expected a port or an object)`)
+	}
+
+	if proxy.Port == 0 {
+		return nil, fmt.Errorf("the proxy requires a port")
+	}
+
+	return proxy, nil
+}
+
+// start identifies a free internal port and starts listening on the public
+// port. Requests are held until Resume is called.
+func (p *devProxy) start() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	p.Upstream = listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	target, _ := url.Parse("http://127.0.0.1:" + strconv.Itoa(p.Upstream))
+	p.reloader = newLivereload()
+	p.gate = make(chan struct{})
+	p.paused = true
+
+	p.proxy = httputil.NewSingleHostReverseProxy(target)
+	p.proxy.FlushInterval = -1
+	director := p.proxy.Director
+	p.proxy.Director = func(r *http.Request) {
+		director(r)
+		// Uncompressed responses allow the live reload script to be injected
+		r.Header.Del("Accept-Encoding")
+	}
+	p.proxy.ModifyResponse = inject
+	p.proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, fmt.Sprintf("the app is unavailable (%v)", err), http.StatusBadGateway)
+	}
+
+	server, err := net.Listen("tcp", fmt.Sprintf(":%d", p.Port))
+	if err != nil {
+		return err
+	}
+
+	go http.Serve(server, p)
+
+	return nil
+}
+
+// Pause holds requests (ex: while the app restarts).
+func (p *devProxy) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.paused {
+		p.gate = make(chan struct{})
+		p.paused = true
+	}
+}
+
+// Resume forwards held (and new) requests to the app. When reload is true,
+// connected browsers are refreshed.
+func (p *devProxy) Resume(reload bool) {
+	p.mu.Lock()
+	if p.paused {
+		close(p.gate)
+		p.paused = false
+	}
+	p.mu.Unlock()
+
+	if reload {
+		p.reloader.Reload()
+	}
+}
+
+func (p *devProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/livereload" {
+		p.reloader.ServeHTTP(w, r)
+		return
+	}
+
+	p.mu.Lock()
+	gate := p.gate
+	p.mu.Unlock()
+
+	select {
+	case <-gate:
+	case <-r.Context().Done():
+		return
+	case <-time.After(p.Timeout):
+		http.Error(w, "the app did not become available in time", http.StatusServiceUnavailable)
+		return
+	}
+
+	p.proxy.ServeHTTP(w, r)
+}
+
+// inject adds the live reload script to HTML pages.
+func inject(res *http.Response) error {
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") || len(res.Header.Get("Content-Encoding")) > 0 {
+		return nil
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	body = injectScript(body)
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+	res.Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return nil
+}
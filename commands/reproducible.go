@@ -0,0 +1,391 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/quikdev/go/context"
+	"github.com/quikdev/go/util"
+)
+
+// verifyReproducible builds the app twice, from copies of the source in
+// separate temporary directories (so file system paths differ), and compares
+// the hashes of the outputs. The copies include the local modules the app
+// depends on (go.work modules and local replace directives), and use the
+// same manifest. Both builds use the same source date epoch and git values.
+func verifyReproducible(ctx *context.Context, file string) error {
+	module, found := util.FindModuleRoot(ctx.CWD)
+	if !found {
+		return fmt.Errorf("cannot verify the build (go.mod not found)")
+	}
+	module, _ = filepath.Abs(module)
+
+	cwd, _ := filepath.Abs(ctx.CWD)
+	src, err := sourceLayout(cwd, module)
+	if err != nil {
+		return fmt.Errorf("cannot verify the build (%v)", err)
+	}
+
+	dir, err := filepath.Rel(src.root, cwd)
+	if err != nil {
+		return err
+	}
+	output, err := filepath.Rel(cwd, ctx.Output())
+	if err != nil {
+		output = ctx.Output()
+	}
+
+	manifest := ctx.GetConfig().File()
+	if !filepath.IsAbs(manifest) {
+		manifest = filepath.Join(cwd, manifest)
+	}
+	manifest, err = filepath.Rel(src.root, manifest)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := reproducibleArgs(os.Args[1:], file, ctx.Target)
+
+	env := os.Environ()
+	if ctx.Reproducible {
+		epoch, err := context.SourceDateEpoch()
+		if err != nil {
+			return err
+		}
+		env = append(env, "SOURCE_DATE_EPOCH="+strconv.FormatInt(epoch.Unix(), 10))
+	} else {
+		util.Stderr("reproducible mode is disabled (set \"reproducible\": true in the manifest)\n")
+	}
+
+	// The copies are not repositories, so git values are resolved here (the
+	// branch is also used by profile conditions)
+	for _, key := range context.GitKeys {
+		if value, _, err := ctx.Resolve("git." + key); err == nil {
+			env = append(env, "QGO_GIT_"+strings.ToUpper(key)+"="+value)
+			if key == "branch" {
+				env = append(env, "GIT_BRANCH="+value)
+			}
+		}
+	}
+
+	hashes := []string{}
+	for i := 1; i <= 2; i++ {
+		tmp, err := os.MkdirTemp("", "qgo-reproducible-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+
+		util.Stdout(fmt.Sprintf("\n# build %d of 2 (%s)\n", i, tmp))
+		if err := src.copy(tmp, filepath.Dir(ctx.Output())); err != nil {
+			return err
+		}
+
+		// The copy of the manifest (and workspace) is used explicitly
+		cmd := exec.Command(exe, args...)
+		cmd.Dir = filepath.Join(tmp, dir)
+		cmd.Env = append(env, "QGO_MANIFEST="+filepath.Join(tmp, manifest))
+		if len(src.gowork) > 0 {
+			cmd.Env = append(cmd.Env, "GOWORK="+filepath.Join(tmp, src.gowork))
+		}
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("build %d failed (%v)", i, err)
+		}
+
+		hash, err := fileHash(filepath.Join(tmp, dir, output))
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	fmt.Println("")
+	util.Stdout(fmt.Sprintf("  ↳ build 1: sha256:%s\n  ↳ build 2: sha256:%s\n", hashes[0], hashes[1]))
+
+	if hashes[0] != hashes[1] {
+		return fmt.Errorf("the builds differ (%s is not reproducible)", output)
+	}
+
+	util.Highlight(fmt.Sprintf("%s is reproducible", output))
+
+	return nil
+}
+
+// reproducibleArgs returns the arguments of the verification builds: the
+// arguments of the current command, for a single build target, without the
+// options that do not apply to the copies. The manifest is provided to the
+// copies with QGO_MANIFEST (the path of its copy).
+func reproducibleArgs(current []string, file string, target string) []string {
+	args := []string{}
+	skip := false
+	for _, arg := range current {
+		if skip {
+			skip = false
+			continue
+		}
+
+		switch {
+		case arg == "--verify-reproducible" || arg == "--no-cache":
+			continue
+		case arg == "--manifest" || arg == "--export":
+			skip = true
+			continue
+		case strings.HasPrefix(arg, "--manifest=") || strings.HasPrefix(arg, "--export="):
+			continue
+		case len(file) > 0 && arg == file && len(target) > 0:
+			continue
+		}
+
+		args = append(args, arg)
+	}
+
+	args = append(args, "--no-cache")
+	if len(target) > 0 {
+		args = append(args, target)
+	}
+
+	return args
+}
+
+// source identifies the directories needed to build a module: the module,
+// the modules of the go.work workspace (when used), and the directories of
+// local replace directives. Paths are relative to root, the closest directory
+// containing all of them, so copies keep the same layout.
+type source struct {
+	root   string
+	dirs   []string // Relative to root
+	files  []string // go.work and go.work.sum (relative to root)
+	gowork string   // The go.work file (relative to root, empty when not used)
+}
+
+// sourceLayout determines the source directories of the module (using go env
+// and go mod/work edit, run from the working directory).
+func sourceLayout(cwd string, module string) (*source, error) {
+	dirs := []string{module}
+	files := []string{}
+
+	out, err := goOutput(cwd, "env", "GOWORK")
+	if err != nil {
+		return nil, err
+	}
+	gowork := strings.TrimSpace(out)
+	if gowork == "off" {
+		gowork = ""
+	}
+
+	if len(gowork) > 0 {
+		files = append(files, gowork)
+		if sum := gowork + ".sum"; util.FileExists(sum) {
+			files = append(files, sum)
+		}
+
+		local, err := localModules(cwd, filepath.Dir(gowork), "work", "edit", "-json", gowork)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, local...)
+	}
+
+	local, err := localModules(module, module, "mod", "edit", "-json")
+	if err != nil {
+		return nil, err
+	}
+	dirs = append(dirs, local...)
+
+	root := module
+	for _, dir := range dirs {
+		root = commonDir(root, dir)
+	}
+	for _, file := range files {
+		root = commonDir(root, filepath.Dir(file))
+	}
+
+	src := &source{root: root}
+
+	// Nested directories are copied with their parent (sorted by length, so
+	// parents come first)
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) < len(dirs[j]) })
+	copied := []string{}
+	for _, dir := range dirs {
+		nested := false
+		for _, parent := range copied {
+			nested = nested || within(parent, dir)
+		}
+		if !nested {
+			copied = append(copied, dir)
+			rel, _ := filepath.Rel(root, dir)
+			src.dirs = append(src.dirs, rel)
+		}
+	}
+	for _, file := range files {
+		rel, _ := filepath.Rel(root, file)
+		src.files = append(src.files, rel)
+	}
+	if len(gowork) > 0 {
+		src.gowork = src.files[0]
+	}
+
+	return src, nil
+}
+
+// localModules returns the directories (absolute) of the modules used by a
+// go.work file (use) or a go.mod file (local replace directives, which have
+// no version).
+func localModules(cwd string, base string, args ...string) ([]string, error) {
+	out, err := goOutput(cwd, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var edit struct {
+		Use []struct {
+			DiskPath string
+		}
+		Replace []struct {
+			New struct {
+				Path    string
+				Version string
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &edit); err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, use := range edit.Use {
+		paths = append(paths, use.DiskPath)
+	}
+	for _, replace := range edit.Replace {
+		if len(replace.New.Version) == 0 {
+			paths = append(paths, replace.New.Path)
+		}
+	}
+
+	dirs := []string{}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(base, path)
+		}
+		dirs = append(dirs, filepath.Clean(path))
+	}
+
+	return dirs, nil
+}
+
+func goOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("go %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(exit.Stderr)))
+		}
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// commonDir returns the closest directory containing a directory and a path.
+func commonDir(dir string, path string) string {
+	for !within(dir, path) && filepath.Dir(dir) != dir {
+		dir = filepath.Dir(dir)
+	}
+
+	return dir
+}
+
+// within determines whether a path is the directory or one of its descendants.
+func within(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// copy copies the source directories and files to a directory (keeping the
+// layout), without the version control data and the build output.
+func (src *source) copy(dest string, output string) error {
+	for _, dir := range src.dirs {
+		if err := copyDir(filepath.Join(src.root, dir), filepath.Join(dest, dir), output); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range src.files {
+		content, err := os.ReadFile(filepath.Join(src.root, file))
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dest, file)), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dest, file), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyDir copies a directory, without the version control data and the build
+// output.
+func copyDir(root string, dest string, output string) error {
+	output, _ = filepath.Abs(output)
+
+	return filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && (info.Name() == ".git" || file == output) {
+			return filepath.SkipDir
+		}
+
+		rel, _ := filepath.Rel(root, file)
+		target := filepath.Join(dest, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, info.Mode().Perm())
+	})
+}
+
+func fileHash(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestReproducibleArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		file     string
+		target   string
+		expected []string
+	}{
+		{
+			"app",
+			[]string{"build", "--verify-reproducible"},
+			"", "",
+			[]string{"build", "--no-cache"},
+		},
+		{
+			"options",
+			[]string{"build", "--no-cache", "--verify-reproducible", "--profile", "release", "-v"},
+			"", "",
+			[]string{"build", "--profile", "release", "-v", "--no-cache"},
+		},
+		{
+			"manifest and export",
+			[]string{"--manifest", "release.json", "build", "--export", "dist", "--manifest=other.json", "--export=site", "--verify-reproducible"},
+			"", "",
+			[]string{"build", "--no-cache"},
+		},
+		{
+			"target",
+			[]string{"build", "server", "--verify-reproducible"},
+			"server", "server",
+			[]string{"build", "--no-cache", "server"},
+		},
+		{
+			"single target",
+			[]string{"build", "--verify-reproducible"},
+			"", "server",
+			[]string{"build", "--no-cache", "server"},
+		},
+		{
+			"file",
+			[]string{"build", "main.go", "--verify-reproducible"},
+			"main.go", "",
+			[]string{"build", "main.go", "--no-cache"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if args := reproducibleArgs(test.args, test.file, test.target); !reflect.DeepEqual(args, test.expected) {
+				t.Errorf("reproducibleArgs(%q) = %q, expected %q", test.args, args, test.expected)
+			}
+		})
+	}
+}
+
+func TestWithin(t *testing.T) {
+	root := filepath.FromSlash("/work/app")
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/work/app", true},
+		{"/work/app/cmd/server", true},
+		{"/work/app/..data", true},
+		{"/work", false},
+		{"/work/application", false},
+		{"/work/lib", false},
+	}
+
+	for _, test := range tests {
+		if inside := within(root, filepath.FromSlash(test.path)); inside != test.expected {
+			t.Errorf("within(%q, %q) = %v, expected %v", root, test.path, inside, test.expected)
+		}
+	}
+
+	if dir := commonDir(root, filepath.FromSlash("/work/libs/lib")); dir != filepath.FromSlash("/work") {
+		t.Errorf("commonDir() = %q, expected /work", dir)
+	}
+}
+
+func TestSourceLayout(t *testing.T) {
+	write := func(dir string, files map[string]string) {
+		for name, content := range files {
+			file := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name  string
+		tree  map[string]string
+		dirs  []string
+		files []string
+	}{
+		{
+			"module",
+			map[string]string{"app/go.mod": "module example.com/app\n\ngo 1.21\n"},
+			[]string{"."}, []string{},
+		},
+		{
+			"replace",
+			map[string]string{
+				"app/go.mod":          "module example.com/app\n\ngo 1.21\n\nreplace example.com/lib => ../libs/lib\n\nreplace example.com/x => example.com/y v1.0.0\n",
+				"libs/lib/go.mod":     "module example.com/lib\n\ngo 1.21\n",
+				"unrelated/README.md": "not copied",
+			},
+			[]string{"app", "libs/lib"}, []string{},
+		},
+		{
+			"workspace",
+			map[string]string{
+				"go.work":             "go 1.21\n\nuse (\n\t./app\n\t./lib\n\t./app/tools\n)\n",
+				"app/go.mod":          "module example.com/app\n\ngo 1.21\n",
+				"app/tools/go.mod":    "module example.com/app/tools\n\ngo 1.21\n",
+				"lib/go.mod":          "module example.com/lib\n\ngo 1.21\n",
+				"unrelated/README.md": "not copied",
+			},
+			[]string{"app", "lib"}, []string{"go.work"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, _ := filepath.EvalSymlinks(t.TempDir())
+			write(dir, test.tree)
+			t.Setenv("GOWORK", "")
+			t.Setenv("GOFLAGS", "")
+
+			app := filepath.Join(dir, "app")
+			src, err := sourceLayout(app, app)
+			if err != nil {
+				t.Fatalf("sourceLayout() error = %v", err)
+			}
+
+			expected := app
+			if len(test.dirs) > 1 {
+				expected = dir
+			}
+			if src.root != expected {
+				t.Errorf("root = %q, expected %q", src.root, expected)
+			}
+
+			dirs := append([]string{}, src.dirs...)
+			for i := range dirs {
+				dirs[i] = filepath.ToSlash(dirs[i])
+			}
+			sort.Strings(dirs)
+			if !reflect.DeepEqual(dirs, test.dirs) {
+				t.Errorf("dirs = %q, expected %q", dirs, test.dirs)
+			}
+			if files := append([]string{}, src.files...); !reflect.DeepEqual(files, test.files) {
+				t.Errorf("files = %q, expected %q", files, test.files)
+			}
+			if (len(src.gowork) > 0) != (len(test.files) > 0) {
+				t.Errorf("gowork = %q", src.gowork)
+			}
+		})
+	}
+}
@@ -3,14 +3,20 @@ package commands
 import "github.com/alecthomas/kong"
 
 var Root struct {
-	Init      Init             `cmd:"init" short:"i" help:"Setup a new Go module or application"`
-	Build     Build            `cmd:"build" short:"b" help:"Build the Go application"`
-	Run       Run              `cmd:"run" short:"r" help:"Run the Go application"`
-	Test      Test             `cmd:"test" short:"t" help:"Run unit tests"`
-	Uninstall Uninstall        `cmd:"uninstall" short:"u" help:"Uninstall a 'go install' app."`
-	Exec      Do               `cmd:"exec" short:"x" help:"Run a script from the manifest"`
-	Bump      Bump             `cmd:"bump" help:"Bump the semantic version number in the manifest"`
-	Todo      Todo             `cmd:"todo" help:"List all of the todo items found in the code base."`
-	Kill      Kill             `cmd:"kill" short:"k" help:"Kill processes by executable name."`
-	Version   kong.VersionFlag `name:"version" short:"v" help:"Display the QuikGo version."`
+	Init         Init             `cmd:"init" short:"i" help:"Setup a new Go module or application"`
+	Build        Build            `cmd:"build" short:"b" help:"Build the Go application"`
+	Run          Run              `cmd:"run" short:"r" help:"Run the Go application"`
+	Dev          Dev              `cmd:"dev" help:"Run several apps/scripts together (with live reload)."`
+	Test         Test             `cmd:"test" short:"t" help:"Run unit tests"`
+	Uninstall    Uninstall        `cmd:"uninstall" short:"u" help:"Uninstall a 'go install' app."`
+	Exec         Do               `cmd:"exec" short:"x" help:"Run a script from the manifest"`
+	Bump         Bump             `cmd:"bump" help:"Bump the semantic version number in the manifest"`
+	Todo         Todo             `cmd:"todo" help:"List all of the todo items found in the code base."`
+	Kill         Kill             `cmd:"kill" short:"k" help:"Kill processes by executable name."`
+	Manifest     Manifest         `cmd:"manifest" help:"Inspect the manifest."`
+	Env          Env              `cmd:"env" help:"Display the environment variables applied to the application (secrets are redacted)."`
+	Variables    Variables        `cmd:"variables" help:"List the string variables that can be set at build time (ldflags -X)."`
+	Size         Size             `cmd:"size" help:"Break down the size of the binary by package and module."`
+	Version      kong.VersionFlag `name:"version" short:"v" help:"Display the QuikGo version."`
+	ManifestFile string           `name:"manifest" env:"QGO_MANIFEST" type:"path" help:"Path to the manifest file (defaults to the nearest manifest in the current or parent directories)."`
 }
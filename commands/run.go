@@ -3,7 +3,6 @@ package commands
 import (
 	"fmt"
 	"net"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,7 +12,10 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/quikdev/go/command"
+	"github.com/quikdev/go/config"
 	"github.com/quikdev/go/context"
+	"github.com/quikdev/go/hook"
 	"github.com/quikdev/go/util"
 
 	fs "github.com/coreybutler/go-fsutil"
@@ -31,6 +33,10 @@ type Run struct {
 	NoWork      bool     `name:"no-work" type:"bool" help:"Set GOWORK=off when building"`
 	Update      bool     `name:"update" short:"u" type:"bool" help:"Update (go mod tidy) before building."`
 	Port        int      `name:"port" short:"p" help:"The port to run the HTTP server on (WASM only)."`
+	Host        string   `name:"host" help:"The address to bind the HTTP server to (WASM only, defaults to all interfaces)."`
+	HTTPS       bool     `name:"https" type:"bool" help:"Serve over HTTPS with a locally generated, self-signed certificate (WASM only)."`
+	Runtime     string   `name:"runtime" default:"browser" enum:"browser,node" help:"Where to run web assemblies: the browser (HTTP server) or Node.js."`
+	Proxy       int      `name:"proxy" help:"Serve the app through a live reload proxy on this port (the app port is provided in the PORT environment variable)."`
 	IgnoreCache bool     `name:"no-cache" type:"bool" help:"Ignore the cache and rebuild, even if no Go files have changed."`
 	Profile     []string `name:"profile" optional:"" help:"Name of the manifest.json profile attribute to apply."`
 	Prekill     bool     `name:"prekill" type:"bool" help:"Run 'qgo kill' before running the command."`
@@ -38,15 +44,43 @@ type Run struct {
 	PostRun     []string `name:"postrun" optional:"" help:"Run a command after running the application."`
 	PreBuild    []string `name:"prebuild" optional:"" help:"Run a command before building the application."`
 	PostBuild   []string `name:"postbuild" optional:"" help:"Run a command after building the application."`
-	File        string   `arg:"source" optional:"" help:"Go source file (ex: main.go)"`
+	File        string   `arg:"source" optional:"" help:"Go source file (ex: main.go) or the name of the build target to run"`
 	Args        []string `arg:"" optional:"" help:"Arguments to pass to the executable."`
 	// Container string `name:"container" default:"docker" type:"string" enum:"docker,podman" help:"The containerization technology to build with"`
 }
 
 func (b *Run) Run(c *Context) error {
 	ctx := context.New(b.Profile...)
+	base := ctx
+
+	// Run a build target (required when the manifest defines more than one)
+	targets := ctx.GetConfig().Targets()
+	name := ""
+	if len(targets) > 0 && len(b.File) > 0 && !strings.HasSuffix(b.File, ".go") {
+		name = b.File
+	} else if len(targets) == 1 {
+		name = targets[0]
+	} else if len(targets) > 1 {
+		util.Stderr(fmt.Sprintf("specify the target to run (ex: qgo run %s) - available targets: %s\n", targets[0], strings.Join(targets, ", ")), true)
+	}
+
+	if len(name) > 0 {
+		target, err := ctx.ForTarget(name)
+		if err != nil {
+			util.Stderr(err, true)
+		}
+		ctx = target
+	}
+
 	ctx.Configure()
 
+	// Hooks provided as flags
+	for stage, cmdlines := range map[string][]string{hook.PreBuild: b.PreBuild, hook.PostBuild: b.PostBuild, hook.PreRun: b.PreRun, hook.PostRun: b.PostRun} {
+		for _, cmdline := range cmdlines {
+			ctx.AddHook(stage, cmdline)
+		}
+	}
+
 	if len(strings.TrimSpace(ctx.InputFile())) == 0 {
 		_, err := util.FindMainFileInDirectory("./")
 		if err != nil {
@@ -65,11 +99,14 @@ func (b *Run) Run(c *Context) error {
 	}
 
 	if b.WASM {
+		ctx.WASM = true
 		ctx.OS = []string{"js"}
 	} else {
 		ctx.OS = b.OS
 	}
 
+	ctx.WASMRuntime = b.Runtime
+
 	if b.Minify {
 		ctx.StripSymbols = true
 		ctx.StripDebugging = true
@@ -80,6 +117,25 @@ func (b *Run) Run(c *Context) error {
 		ctx.GCCGoFlags.Add("-w")
 	}
 
+	// Serve the app through a reverse proxy that hides restarts
+	if !b.DryRun && !b.WASM && !ctx.WASM {
+		proxy, err := b.proxy(ctx)
+		if err != nil {
+			util.Stderr(err, true)
+		}
+		if proxy != nil {
+			return b.runProxied(base, name, proxy)
+		}
+	}
+
+	// Generate the build information package (when enabled)
+	if !b.DryRun {
+		util.BailOnError(ctx.GenerateBuildInfo())
+	}
+
+	// Warn about (or stop on) variables that cannot be linked
+	ctx.ValidateVariables()
+
 	// Run this before go mod tidy to determine whether the output is cached
 	cmd := ctx.RunCommand()
 	if c.Debug {
@@ -110,15 +166,8 @@ func (b *Run) Run(c *Context) error {
 		}
 
 		// Pre-build
-		if ctx.PreBuild != nil && !ctx.Cached {
-			for _, precmd := range ctx.PreBuild {
-				if hide {
-					util.BailOnError(util.StreamNoHighlight(precmd))
-				} else {
-					util.BailOnError(util.Stream(precmd))
-				}
-				fmt.Println("")
-			}
+		if !ctx.Cached {
+			ctx.RunHooks(ctx.Event(hook.PreBuild), hide)
 		}
 
 		if ctx.Tidy {
@@ -131,36 +180,54 @@ func (b *Run) Run(c *Context) error {
 			}
 		}
 
-		vars := ctx.GetConfig().GetEnvVars()
-		if len(vars) > 0 {
+		vars, err := ctx.GetConfig().Environment()
+		util.BailOnError(err)
+
+		applied := []*config.EnvVar{}
+		for _, v := range vars {
+			if v.Source != "process" {
+				applied = append(applied, v)
+			}
+		}
+
+		if len(applied) > 0 {
 			util.Stdout(`# autoapplying the following environment variables` + "\n")
-			for key, value := range vars {
-				os.Setenv(key, value)
-				util.Stdout("\n  " + strings.Replace(util.SubtleHighlighter(key), "\n", "", 1) + util.Dim("=") + strings.ReplaceAll(util.Highlighter(value), "\n", ""))
+			for _, v := range applied {
+				os.Setenv(v.Name, v.Value)
+				util.Stdout("\n  " + strings.Replace(util.SubtleHighlighter(v.Name), "\n", "", 1) + util.Dim("=") + strings.ReplaceAll(util.Highlighter(strings.ReplaceAll(v.Masked(), "\n", `\n`)), "\n", ""))
 			}
 			fmt.Printf("\n\n")
 		}
 	}
 
-	// Post-build
-	if ctx.PostBuild != nil {
-		for _, postcmd := range ctx.PostBuild {
-			cmd.InjectCommand(0, postcmd, false)
-		}
+	// The build is skipped when cached, making the app the first command
+	build, run := 0, 1
+	if ctx.Cached {
+		build, run = -1, 0
+	}
+
+	// Optimize web assemblies before the post-build hooks, which receive the
+	// outcome of the build
+	if build >= 0 {
+		injectWASMOptimization(ctx, cmd, build)
+		injectPostBuildHooks(ctx, cmd, build, hide)
 	}
 
 	// Pre-run
-	if ctx.PreRun != nil {
-		for _, precmd := range ctx.PreRun {
-			cmd.InjectCommand(1, precmd, true)
-		}
+	if len(ctx.PreRun) > 0 {
+		cmd.InjectCommand(run, true, func(step command.Step) {
+			ctx.RunHooks(ctx.Event(hook.PreRun), hide)
+		})
 	}
 
-	// Post-run
-	if ctx.PostRun != nil {
-		for _, postcmd := range ctx.PostRun {
-			cmd.InjectCommand(1, postcmd, false)
-		}
+	// Post-run (receives the outcome of the run)
+	if len(ctx.PostRun) > 0 {
+		cmd.InjectCommand(run, false, func(step command.Step) {
+			event := ctx.Event(hook.PostRun)
+			event.DurationMS = step.Duration.Milliseconds()
+			event.Success = step.Success
+			ctx.RunHooks(event, hide)
+		})
 	}
 
 	if !hide {
@@ -182,7 +249,7 @@ func (b *Run) Run(c *Context) error {
 			os.Setenv("GOWORK", "off")
 		}
 
-		subscribers := make(map[chan string]bool)
+		reloader := newLivereload()
 		reload, livereloadexists := ctx.GetConfig().Get("livereload")
 		if !livereloadexists {
 			tmp := make([]interface{}, 2)
@@ -219,18 +286,24 @@ func (b *Run) Run(c *Context) error {
 									}
 
 									if event.Has(fsnotify.Write) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) || event.Has(fsnotify.Create) {
-										if ctx.WASM {
+										if ctx.Browser() {
 											if !ignoreEvents && event.Has(fsnotify.Write) {
 												go func() {
 													ignoreEvents = true
 													ctx.IgnoreCache = true
 													cmd = ctx.BuildCommand()
+													injectWASMOptimization(ctx, cmd, 0)
+													injectPostBuildHooks(ctx, cmd, 0, hide)
 													fmt.Println(cmd.Display())
-													cmd.Run(ctx.CWD)
-													// fmt.Println("execution complete")
-													time.Sleep(500 * time.Millisecond)
-													for subscriber := range subscribers {
-														subscriber <- "reload"
+
+													// Build errors are displayed in the browser
+													output, ok := cmd.Try(ctx.CWD)
+													if ok {
+														time.Sleep(500 * time.Millisecond)
+														reloader.Clear()
+														reloader.Reload()
+													} else {
+														reloader.Error(output)
 													}
 													time.Sleep(3 * time.Second)
 													ignoreEvents = false
@@ -263,9 +336,15 @@ func (b *Run) Run(c *Context) error {
 			}
 		}
 
-		if ctx.WASM {
+		// Browser modules are served, others are run by a WASI runtime or Node.js
+		if ctx.Browser() {
 			cmd.Run(ctx.CWD)
 
+			// Keep wasm_exec.js in sync with the toolchain
+			if _, err := ctx.UpdateWASMExec(); err != nil {
+				util.Stderr(err)
+			}
+
 			root := filepath.Dir(ctx.Output())
 
 			port := b.Port
@@ -282,45 +361,22 @@ func (b *Run) Run(c *Context) error {
 
 			wg.Add(1)
 
-			server := http.FileServer(http.Dir(root))
-
-			http.HandleFunc("/livereload", func(w http.ResponseWriter, r *http.Request) {
-				util.SubtleHighlight(r.Header.Get("User-Agent") + " connected")
-				w.Header().Set("Access-Control-Allow-Origin", "*")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-				w.Header().Set("Content-Type", "text/event-stream")
-				w.Header().Set("Cache-Control", "no-cache")
-				w.Header().Set("Connection", "keep-alive")
-
-				clientChan := make(chan string)
-				subscribers[clientChan] = true
-				defer func() { delete(subscribers, clientChan) }()
-				defer r.Body.Close()
-
-				for {
-					select {
-					case event := <-clientChan:
-						// fmt.Println(`sent: ` + event)
-						w.Write([]byte("data: " + event + "\n\n"))
-						w.(http.Flusher).Flush()
-					case <-r.Context().Done():
-						delete(subscribers, clientChan)
-						util.Stdout("\n" + r.UserAgent() + " disconnected")
-						return
-					}
-				}
-			})
-
-			http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-				if r.URL.Path == "/" {
-					http.ServeFile(w, r, filepath.Join(root, "index.html"))
-					return
-				}
+			server := newDevServer(root, reloader)
+			server.Port = port
+			if value, exists := ctx.GetConfig().Get("server"); exists {
+				util.BailOnError(server.configure(value))
+			}
+			if len(b.Host) > 0 {
+				server.Host = b.Host
+			}
+			if b.HTTPS {
+				server.HTTPS = true
+			}
 
-				server.ServeHTTP(w, r)
-			})
+			url := server.URL()
 
-			url := fmt.Sprintf("http://localhost:%d", port)
+			// Stylesheets are swapped and other assets reloaded when they change
+			go server.watch()
 
 			// Launch test server
 			go func() {
@@ -328,7 +384,7 @@ func (b *Run) Run(c *Context) error {
 				fmt.Println("")
 				util.HighlightComment("launching QuikGo HTTP server...")
 				util.Stdout(fmt.Sprintf("server available at %s\nctrl+c or cmd+c to quit\n", url))
-				util.BailOnError(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
+				util.BailOnError(server.ListenAndServe())
 			}()
 
 			// Optionally open browser
@@ -382,3 +438,46 @@ func openbrowser(url string) {
 		exec.Command(cmd, url).Start()
 	}
 }
+
+// proxy returns the live reload proxy configuration (from the --proxy flag or
+// the "proxy" manifest attribute), or nil when the proxy is not enabled.
+func (b *Run) proxy(ctx *context.Context) (*devProxy, error) {
+	var proxy *devProxy
+	if value, exists := ctx.GetConfig().Get("proxy"); exists {
+		p, err := parseProxy(value)
+		if err != nil {
+			return nil, err
+		}
+		proxy = p
+	}
+
+	if b.Proxy > 0 {
+		if proxy == nil {
+			proxy, _ = parseProxy(float64(b.Proxy))
+		}
+		proxy.Port = b.Proxy
+	}
+
+	return proxy, nil
+}
+
+// runProxied runs the app (or build target) under the dev supervisor, which
+// rebuilds and restarts it when files change while the proxy holds requests.
+func (b *Run) runProxied(ctx *context.Context, target string, proxy *devProxy) error {
+	svc := newDevService("app")
+	if len(target) > 0 {
+		svc.Name = target
+		svc.Target = target
+	} else {
+		svc.App = true
+	}
+	svc.Args = b.Args
+	svc.proxy = proxy
+
+	exe, err := os.Executable()
+	util.BailOnError(err)
+
+	s := &supervisor{ctx: ctx, services: []*devService{svc}, profiles: b.Profile, exe: exe}
+
+	return s.run()
+}
@@ -0,0 +1,554 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/quikdev/go/context"
+	"github.com/quikdev/go/util"
+)
+
+type Size struct {
+	Top      int      `name:"top" short:"n" default:"20" help:"The number of packages to display (largest first)."`
+	Baseline string   `name:"baseline" type:"path" help:"Compare with a saved report (defaults to the size.baseline manifest attribute, or the previous report)."`
+	Save     string   `name:"save" type:"path" help:"Save the report as a baseline file."`
+	NoBuild  bool     `name:"no-build" type:"bool" help:"Analyze the existing binary without building it."`
+	JSON     bool     `name:"json" type:"bool" help:"Output the report as JSON."`
+	Profile  []string `name:"profile" optional:"" help:"Name of the manifest.json profile attribute to apply."`
+	File     string   `arg:"source" optional:"" help:"Go source file (ex: main.go) or the name of the build target to analyze"`
+}
+
+// sizeReport breaks down the size of a binary by package and module.
+type sizeReport struct {
+	Binary   string            `json:"binary"`
+	Created  time.Time         `json:"created"`
+	File     uint64            `json:"file"`    // Size of the binary
+	Symbols  uint64            `json:"symbols"` // Size of the code and data symbols
+	Packages map[string]uint64 `json:"packages,omitempty"`
+	Modules  map[string]uint64 `json:"modules,omitempty"`
+	Versions map[string]string `json:"versions,omitempty"` // Module versions
+	Note     string            `json:"note,omitempty"`     // Explains why symbol sizes are not available
+}
+
+// sizeBudget limits the size of the binary ("size": {"budget": ...}). Sizes are
+// numbers (bytes) or strings (ex: "8 MB").
+//
+//	"budget": "8MB"
+//	"budget": {"total": "8MB", "modules": {"github.com/aws/aws-sdk-go-v2": "2MB"}, "packages": {"main": "200kB"}}
+type sizeBudget struct {
+	Total    uint64
+	Modules  map[string]uint64
+	Packages map[string]uint64
+}
+
+func (s *Size) Run(c *Context) error {
+	ctx := context.New(s.Profile...)
+
+	// Analyze a build target (required when the manifest defines more than one)
+	targets := ctx.GetConfig().Targets()
+	name := ""
+	if len(targets) > 0 && len(s.File) > 0 && !strings.HasSuffix(s.File, ".go") {
+		name = s.File
+	} else if len(targets) == 1 {
+		name = targets[0]
+	} else if len(targets) > 1 {
+		util.Stderr(fmt.Sprintf("specify the target to analyze (ex: qgo size %s) - available targets: %s\n", targets[0], strings.Join(targets, ", ")), true)
+	}
+
+	if len(name) > 0 {
+		target, err := ctx.ForTarget(name)
+		if err != nil {
+			util.Stderr(err, true)
+		}
+		ctx = target
+	}
+
+	if s.NoBuild {
+		ctx.Configure()
+	} else {
+		b := &Build{Profile: s.Profile, analyze: true}
+		if err := b.build(c, ctx); err != nil {
+			return err
+		}
+	}
+
+	report, err := analyzeSize(ctx, true)
+	util.BailOnError(err)
+
+	// Compare with the baseline or the previous report
+	baselineFile := s.Baseline
+	if len(baselineFile) == 0 {
+		if value, exists := ctx.GetConfig().Get("size.baseline"); exists {
+			baselineFile = filepath.Join(ctx.CWD, fmt.Sprintf("%v", value))
+		}
+	}
+
+	label := "baseline"
+	previousFile := previousSizeReport(report.Binary)
+	if len(baselineFile) == 0 || !util.FileExists(baselineFile) {
+		if len(s.Baseline) > 0 {
+			util.Stderr(fmt.Sprintf("baseline %s not found\n", s.Baseline), true)
+		}
+		baselineFile = previousFile
+		label = "previous report"
+	}
+
+	var baseline *sizeReport
+	if util.FileExists(baselineFile) {
+		baseline, err = readSizeReport(baselineFile)
+		if err != nil {
+			util.Stderr(fmt.Sprintf("cannot read %s (%v)\n", baselineFile, err))
+		}
+	}
+
+	if s.JSON {
+		content, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(content))
+	} else {
+		report.Print(baseline, label, s.Top)
+	}
+
+	// The previous report is replaced by this one
+	if err := report.Save(previousFile); err != nil {
+		util.Stderr(fmt.Sprintf("cannot save the report (%v)\n", err))
+	}
+
+	if len(s.Save) > 0 {
+		util.BailOnError(report.Save(s.Save))
+		util.SubtleHighlight("saved baseline to " + s.Save)
+	}
+
+	budget, err := parseSizeBudget(ctx)
+	util.BailOnError(err)
+	if budget != nil {
+		enforceSizeBudget(budget, report)
+	}
+
+	return nil
+}
+
+// analyzeSize measures the binary. Symbol sizes are broken down by package
+// and module when detailed is true (using go tool nm). Web assemblies are
+// not supported by go tool nm, so only the file size is reported (with a note).
+func analyzeSize(ctx *context.Context, detailed bool) (*sizeReport, error) {
+	binary, _ := filepath.Abs(ctx.Output())
+	info, err := os.Stat(binary)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found (build it first)", ctx.Output())
+	}
+
+	report := &sizeReport{
+		Binary:   binary,
+		Created:  time.Now().UTC(),
+		File:     uint64(info.Size()),
+		Packages: map[string]uint64{},
+		Modules:  map[string]uint64{},
+		Versions: map[string]string{},
+	}
+
+	if !detailed {
+		return report, nil
+	}
+
+	if ctx.WASM {
+		report.Note = "symbol sizes are not available for web assemblies (go tool nm does not read WASM files)"
+		return report, nil
+	}
+
+	out, err := exec.Command("go", "tool", "nm", "-size", "-sort", "size", binary).Output()
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("go tool nm failed: %s", strings.TrimSpace(string(exit.Stderr)))
+		}
+		return nil, fmt.Errorf("go tool nm failed (%v)", err)
+	}
+
+	// Modules compiled into the binary (the main module contains package main)
+	main, modules := binaryModules(binary, ctx.CWD, report.Versions)
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		// address size type name (the name may contain spaces)
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+
+		size, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil || size == 0 {
+			continue
+		}
+
+		// Uninitialized data (bss) does not occupy space in the file
+		switch fields[2] {
+		case "T", "t", "D", "d", "R", "r":
+		default:
+			continue
+		}
+
+		pkg := symbolPackage(strings.Join(fields[3:], " "))
+		report.Symbols += size
+		report.Packages[pkg] += size
+
+		module := "std"
+		switch {
+		case pkg == "main":
+			module = main
+		case pkg == "(other)":
+			module = "(other)"
+		default:
+			for _, m := range modules {
+				if pkg == m || strings.HasPrefix(pkg, m+"/") {
+					module = m
+					break
+				}
+			}
+			if module == "std" && strings.Contains(strings.Split(pkg, "/")[0], ".") {
+				module = "(unknown)"
+			}
+		}
+		report.Modules[module] += size
+	}
+
+	return report, scanner.Err()
+}
+
+// binaryModules reads the main module and the dependency modules from the
+// build information of a binary (go version -m). Binaries built from a file
+// (ex: go build main.go) do not identify the main module, so it is read from
+// the go.mod file of the working directory. The modules are sorted by length
+// (longest first), so nested modules are matched first.
+func binaryModules(binary string, dir string, versions map[string]string) (string, []string) {
+	main := "main"
+	modules := []string{}
+
+	out, err := exec.Command("go", "version", "-m", binary).Output()
+	if err != nil {
+		return main, modules
+	}
+
+	if !strings.Contains(string(out), "\tmod\t") {
+		cmd := exec.Command("go", "list", "-m")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOWORK=off")
+		if module, err := cmd.Output(); err == nil && len(strings.TrimSpace(string(module))) > 0 {
+			main = strings.TrimSpace(string(module))
+			modules = append(modules, main)
+		}
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "mod":
+			main = fields[1]
+			modules = append(modules, fields[1])
+		case "dep":
+			modules = append(modules, fields[1])
+			if len(fields) > 2 {
+				versions[fields[1]] = fields[2]
+			}
+		}
+	}
+
+	sort.Slice(modules, func(i, j int) bool { return len(modules[i]) > len(modules[j]) })
+
+	return main, modules
+}
+
+// symbolPackage returns the import path of the package that defines a symbol.
+// Symbols that do not belong to a package (ex: type descriptors of built-in
+// types, string data) are grouped as "(other)".
+func symbolPackage(name string) string {
+	for _, prefix := range []string{"type:", "go:itab.", "go:info."} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	name = strings.TrimLeft(name, "*")
+
+	// Ignore the receiver, type parameters, and anything after them
+	if i := strings.IndexAny(name, "([ "); i >= 0 {
+		name = name[:i]
+	}
+
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if strings.HasPrefix(name, "go:") || dot <= 0 {
+		return "(other)"
+	}
+
+	// Dots of the last path element are escaped (ex: gopkg.in/yaml%2ev3)
+	pkg := name[:slash+1+dot]
+	for strings.Contains(pkg, "%") {
+		unescaped, err := url.PathUnescape(pkg)
+		if err != nil || unescaped == pkg {
+			break
+		}
+		pkg = unescaped
+	}
+
+	return pkg
+}
+
+// previousSizeReport returns the path of the report saved by the previous
+// analysis of a binary (in the user cache directory).
+func previousSizeReport(binary string) string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+
+	sum := sha256.Sum256([]byte(binary))
+	return filepath.Join(cache, "qgo", "size", hex.EncodeToString(sum[:])[:16]+".json")
+}
+
+func readSizeReport(file string) (*sizeReport, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var report sizeReport
+	err = json.Unmarshal(content, &report)
+	return &report, err
+}
+
+// Save writes the report as JSON.
+func (r *sizeReport) Save(file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+
+	content, _ := json.MarshalIndent(r, "", "  ")
+	return os.WriteFile(file, content, 0644)
+}
+
+// Print displays the modules and the largest packages, with the change
+// since the baseline (when available).
+func (r *sizeReport) Print(baseline *sizeReport, label string, top int) {
+	dim := color.New(color.Faint).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	delta := func(current uint64, previous uint64, exists bool) string {
+		switch {
+		case !exists:
+			return red("new")
+		case current > previous:
+			return red("+" + humanize.Bytes(current-previous))
+		case current < previous:
+			return green("-" + humanize.Bytes(previous-current))
+		}
+		return dim("=")
+	}
+
+	if len(r.Note) > 0 {
+		util.Stdout(fmt.Sprintf("# %s: %s\n", r.Binary, humanize.Bytes(r.File)))
+	} else {
+		util.Stdout(fmt.Sprintf("# %s: %s (symbols: %s)\n", r.Binary, humanize.Bytes(r.File), humanize.Bytes(r.Symbols)))
+	}
+	if baseline != nil {
+		fmt.Printf("  compared to the %s (%s): %s\n", label, baseline.Created.Local().Format("2006-01-02 15:04"), delta(r.File, baseline.File, true))
+	}
+
+	// Without symbols, there is nothing to break down
+	if len(r.Note) > 0 {
+		fmt.Println("  " + dim(r.Note) + "\n")
+		return
+	}
+
+	table := func(title string, sizes map[string]uint64, previous map[string]uint64, versions map[string]string, limit int, removed bool) {
+		names := make([]string, 0, len(sizes))
+		for name := range sizes {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if sizes[names[i]] == sizes[names[j]] {
+				return names[i] < names[j]
+			}
+			return sizes[names[i]] > sizes[names[j]]
+		})
+
+		if limit > 0 && len(names) > limit {
+			title = fmt.Sprintf("%s (top %d of %d)", title, limit, len(names))
+			names = names[:limit]
+		}
+
+		labels := map[string]string{}
+		width := 0
+		for _, name := range names {
+			labels[name] = name
+			if version, ok := versions[name]; ok {
+				labels[name] += " " + version
+			}
+			if len(labels[name]) > width {
+				width = len(labels[name])
+			}
+		}
+
+		fmt.Print("\n" + util.Highlighter(title))
+		for _, name := range names {
+			line := fmt.Sprintf("  %-*s  %9s  %5.1f%%", width, labels[name], humanize.Bytes(sizes[name]), float64(sizes[name])*100/float64(r.Symbols))
+			if baseline != nil {
+				size, exists := previous[name]
+				line += "  " + delta(sizes[name], size, exists)
+			}
+			fmt.Println(line)
+		}
+
+		// Items of the baseline that no longer exist
+		if removed && baseline != nil {
+			gone := []string{}
+			for name := range previous {
+				if _, exists := sizes[name]; !exists {
+					gone = append(gone, name)
+				}
+			}
+			sort.Strings(gone)
+			for _, name := range gone {
+				fmt.Printf("  %s  %s\n", dim(fmt.Sprintf("%-*s", width, name)), green("removed (-"+humanize.Bytes(previous[name])+")"))
+			}
+		}
+	}
+
+	var previousModules, previousPackages map[string]uint64
+	if baseline != nil {
+		previousModules, previousPackages = baseline.Modules, baseline.Packages
+	}
+
+	table("Modules", r.Modules, previousModules, r.Versions, 0, true)
+	table("Packages", r.Packages, previousPackages, nil, top, false)
+
+	fmt.Println("")
+}
+
+// parseSizeBudget reads the size budget from the manifest (nil when none is
+// configured).
+func parseSizeBudget(ctx *context.Context) (*sizeBudget, error) {
+	value, exists := ctx.GetConfig().Get("size.budget")
+	if !exists {
+		return nil, nil
+	}
+
+	budget := &sizeBudget{Modules: map[string]uint64{}, Packages: map[string]uint64{}}
+
+	if settings, ok := value.(map[string]interface{}); ok {
+		if total, exists := settings["total"]; exists {
+			size, err := parseByteSize(total)
+			if err != nil {
+				return nil, err
+			}
+			budget.Total = size
+		}
+
+		for key, limits := range map[string]map[string]uint64{"modules": budget.Modules, "packages": budget.Packages} {
+			items, ok := settings[key].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for name, limit := range items {
+				size, err := parseByteSize(limit)
+				if err != nil {
+					return nil, err
+				}
+				limits[name] = size
+			}
+		}
+
+		return budget, nil
+	}
+
+	size, err := parseByteSize(value)
+	if err != nil {
+		return nil, err
+	}
+	budget.Total = size
+
+	return budget, nil
+}
+
+// Detailed determines whether the budget limits modules or packages, which
+// requires a symbol analysis.
+func (b *sizeBudget) Detailed() bool {
+	return len(b.Modules) > 0 || len(b.Packages) > 0
+}
+
+// Check returns the limits exceeded by a report.
+func (b *sizeBudget) Check(report *sizeReport) []string {
+	exceeded := []string{}
+
+	if b.Total > 0 && report.File > b.Total {
+		exceeded = append(exceeded, fmt.Sprintf("%s is %s (budget: %s)", filepath.Base(report.Binary), humanize.Bytes(report.File), humanize.Bytes(b.Total)))
+	}
+
+	for _, group := range []struct {
+		limits map[string]uint64
+		sizes  map[string]uint64
+	}{{b.Modules, report.Modules}, {b.Packages, report.Packages}} {
+		names := make([]string, 0, len(group.limits))
+		for name := range group.limits {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if group.sizes[name] > group.limits[name] {
+				exceeded = append(exceeded, fmt.Sprintf("%s is %s (budget: %s)", name, humanize.Bytes(group.sizes[name]), humanize.Bytes(group.limits[name])))
+			}
+		}
+	}
+
+	return exceeded
+}
+
+// enforceSizeBudget exits when the report exceeds the budget. Module and
+// package limits are not enforced without symbol sizes (web assemblies).
+func enforceSizeBudget(budget *sizeBudget, report *sizeReport) {
+	if budget.Detailed() && len(report.Note) > 0 {
+		util.Stderr(fmt.Sprintf("warning: module and package size budgets are not enforced: %s\n", report.Note))
+	}
+
+	exceeded := budget.Check(report)
+	if len(exceeded) == 0 {
+		return
+	}
+
+	util.Stderr("size budget exceeded:\n")
+	for _, item := range exceeded {
+		util.Stderr("  " + item + "\n")
+	}
+	os.Exit(1)
+}
+
+func parseByteSize(value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case float64:
+		return uint64(v), nil
+	case string:
+		size, err := humanize.ParseBytes(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid size budget \"%s\" (%v)", v, err)
+		}
+		return size, nil
+	}
+
+	return 0, fmt.Errorf("invalid size budget %v (expected a number of bytes or a size such as \"8 MB\")", value)
+}
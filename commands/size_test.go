@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/quikdev/go/context"
+)
+
+// newTestContext creates the context of a manifest written to a temporary
+// directory (which becomes the working directory).
+func newTestContext(t *testing.T, manifest string) *context.Context {
+	t.Helper()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "manifest.json")
+	if err := os.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("QGO_MANIFEST", file)
+
+	return context.New()
+}
+
+func TestSymbolPackage(t *testing.T) {
+	tests := []struct {
+		symbol   string
+		expected string
+	}{
+		{"main.main", "main"},
+		{"runtime.mallocgc", "runtime"},
+		{"net/http.(*Server).Serve", "net/http"},
+		{"github.com/org/app/internal/db.Open", "github.com/org/app/internal/db"},
+		{"github.com/org/app/internal/db.(*Conn).Query", "github.com/org/app/internal/db"},
+		{"gopkg.in/yaml%2ev3.Unmarshal", "gopkg.in/yaml.v3"},
+		{"type:*github.com/org/app/config.Settings", "github.com/org/app/config"},
+		{"go:itab.*os.File,io.Writer", "os"},
+		{"slices.Sort[go.shape.string]", "slices"},
+		{"sync.(*Pool).Get", "sync"},
+		{"type:string", "(other)"},
+		{"go:string.*", "(other)"},
+		{"runtime", "(other)"},
+	}
+
+	for _, test := range tests {
+		if pkg := symbolPackage(test.symbol); pkg != test.expected {
+			t.Errorf("symbolPackage(%q) = %q, expected %q", test.symbol, pkg, test.expected)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected uint64
+		valid    bool
+	}{
+		{float64(1024), 1024, true},
+		{"8 MB", 8000000, true},
+		{"8MB", 8000000, true},
+		{"200kB", 200000, true},
+		{"2 MiB", 2097152, true},
+		{"big", 0, false},
+		{true, 0, false},
+		{nil, 0, false},
+	}
+
+	for _, test := range tests {
+		size, err := parseByteSize(test.value)
+		if (err == nil) != test.valid || size != test.expected {
+			t.Errorf("parseByteSize(%v) = %d, %v, expected %d (valid: %v)", test.value, size, err, test.expected, test.valid)
+		}
+	}
+}
+
+func TestParseSizeBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		expected *sizeBudget
+		valid    bool
+	}{
+		{"none", `{"name": "app"}`, nil, true},
+		{
+			"total",
+			`{"name": "app", "size": {"budget": "20 MB"}}`,
+			&sizeBudget{Total: 20000000, Modules: map[string]uint64{}, Packages: map[string]uint64{}},
+			true,
+		},
+		{
+			"limits",
+			`{"name": "app", "size": {"budget": {"total": 1000, "modules": {"github.com/aws/aws-sdk-go-v2": "2 MB"}, "packages": {"main": "200 kB"}}}}`,
+			&sizeBudget{Total: 1000, Modules: map[string]uint64{"github.com/aws/aws-sdk-go-v2": 2000000}, Packages: map[string]uint64{"main": 200000}},
+			true,
+		},
+		{"invalid total", `{"name": "app", "size": {"budget": "lots"}}`, nil, false},
+		{"invalid limit", `{"name": "app", "size": {"budget": {"packages": {"main": "lots"}}}}`, nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			budget, err := parseSizeBudget(newTestContext(t, test.manifest))
+			if (err == nil) != test.valid {
+				t.Fatalf("parseSizeBudget() error = %v, expected valid: %v", err, test.valid)
+			}
+			if !reflect.DeepEqual(budget, test.expected) {
+				t.Errorf("parseSizeBudget() = %+v, expected %+v", budget, test.expected)
+			}
+		})
+	}
+}
+
+func TestSizeBudgetCheck(t *testing.T) {
+	report := &sizeReport{
+		Binary:   "/project/bin/app",
+		File:     10000000,
+		Modules:  map[string]uint64{"github.com/aws/aws-sdk-go-v2": 3000000, "std": 4000000},
+		Packages: map[string]uint64{"main": 150000, "net/http": 900000},
+	}
+
+	tests := []struct {
+		name     string
+		budget   *sizeBudget
+		exceeded []string
+		detailed bool
+	}{
+		{"within", &sizeBudget{Total: 20000000}, []string{}, false},
+		{"total", &sizeBudget{Total: 8000000}, []string{"app is 10 MB (budget: 8.0 MB)"}, false},
+		{
+			"limits",
+			&sizeBudget{
+				Modules:  map[string]uint64{"github.com/aws/aws-sdk-go-v2": 2000000, "std": 5000000},
+				Packages: map[string]uint64{"main": 200000, "net/http": 500000, "missing": 1},
+			},
+			[]string{"github.com/aws/aws-sdk-go-v2 is 3.0 MB (budget: 2.0 MB)", "net/http is 900 kB (budget: 500 kB)"},
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if exceeded := test.budget.Check(report); !reflect.DeepEqual(exceeded, test.exceeded) {
+				t.Errorf("Check() = %q, expected %q", exceeded, test.exceeded)
+			}
+			if detailed := test.budget.Detailed(); detailed != test.detailed {
+				t.Errorf("Detailed() = %v, expected %v", detailed, test.detailed)
+			}
+		})
+	}
+}
+
+func TestAnalyzeSizeWASM(t *testing.T) {
+	ctx := newTestContext(t, `{"name": "app", "build": "main.go", "wasm": true}`)
+	ctx.Configure()
+
+	output := ctx.Output()
+	if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(output, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := analyzeSize(ctx, true)
+	if err != nil {
+		t.Fatalf("analyzeSize() error = %v", err)
+	}
+	if report.File != 4096 || report.Symbols != 0 || len(report.Packages) != 0 {
+		t.Errorf("analyzeSize() = file %d, symbols %d, %d packages, expected the file size only", report.File, report.Symbols, len(report.Packages))
+	}
+	if !strings.Contains(report.Note, "web assemblies") {
+		t.Errorf("analyzeSize() note = %q, expected an explanation", report.Note)
+	}
+}
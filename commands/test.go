@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -26,11 +27,24 @@ var (
 
 type Test struct {
 	Format string `name:"format" short:"f" default:"spec" help:"The format to diplay test results in. Defaults to 'spec', a TAP visualizer. Options include 'tap', 'spec', 'json', and 'go' (i.e. go test standard)"`
+	All    bool   `name:"all" type:"bool" help:"Test every module in the go.work workspace, in dependency order."`
+	Jobs   int    `name:"jobs" short:"j" help:"The number of modules to test in parallel with --all (defaults to the number of CPUs)."`
+	WASM   bool   `name:"wasm" type:"bool" help:"Compile the tests to web assemblies (GOOS=js GOARCH=wasm) and run them with Node.js."`
 }
 
 func (t *Test) Run(c *Context) error {
+	if t.All {
+		return runWorkspace(t.Jobs, false)
+	}
+
 	ctx := context.New()
 	ctx.Configure()
+	util.BailOnError(ctx.GenerateBuildInfo())
+
+	// Apply the manifest environment variables (and env files)
+	for key, value := range ctx.GetConfig().GetEnvVars() {
+		os.Setenv(key, value)
+	}
 
 	format := strings.ToLower(t.Format)
 
@@ -39,7 +53,26 @@ func (t *Test) Run(c *Context) error {
 		args = append(args, "-json")
 	}
 
+	// Run js/wasm test binaries with Node.js (the equivalent of go_js_wasm_exec)
+	env := os.Environ()
+	if t.WASM {
+		launcher, err := context.WASMNode()
+		if err != nil {
+			util.Stderr(err, true)
+		}
+
+		for i, part := range launcher {
+			if strings.ContainsAny(part, " \t") {
+				launcher[i] = `"` + part + `"`
+			}
+		}
+
+		args = append(args, "-exec", strings.Join(launcher, " "))
+		env = append(env, "GOOS=js", "GOARCH=wasm")
+	}
+
 	cmd := exec.Command("go", args...)
+	cmd.Env = env
 
 	stdout, err := cmd.StdoutPipe()
 	util.BailOnError(err)
@@ -73,6 +106,11 @@ func (t *Test) Run(c *Context) error {
 
 	wg.Wait()
 
+	// Failing tests result in a non-zero exit code
+	if err := cmd.Wait(); err != nil {
+		os.Exit(1)
+	}
+
 	return nil
 }
 
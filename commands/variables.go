@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/quikdev/go/context"
+	"github.com/quikdev/go/util"
+)
+
+type Variables struct {
+	All      bool     `name:"all" short:"a" type:"bool" help:"Include package-level variables that cannot be set with -X."`
+	Profile  []string `name:"profile" optional:"" help:"Name of the manifest.json profile attribute to apply."`
+	Packages []string `arg:"package" optional:"" help:"Package(s) to inspect (defaults to main and the packages referenced in the manifest variables)."`
+}
+
+func (v *Variables) Run(c *Context) error {
+	ctx := context.New(v.Profile...)
+	ctx.Configure()
+
+	// Variables configured in the manifest
+	configured := map[string]string{}
+	if list, exists := ctx.GetConfig().Get("variables"); exists {
+		if variables, ok := list.(map[string]interface{}); ok {
+			for target, value := range variables {
+				configured[target] = fmt.Sprintf("%v", value)
+			}
+		}
+	}
+
+	packages := v.Packages
+	if len(packages) == 0 {
+		packages = []string{"main"}
+		targets := make([]string, 0, len(configured))
+		for target := range configured {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
+
+		for _, target := range targets {
+			i := strings.LastIndex(target, ".")
+			if i > 0 && !util.InSlice[string](target[:i], packages) {
+				packages = append(packages, target[:i])
+			}
+		}
+	}
+
+	dim := color.New(color.Faint).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	for _, pkg := range packages {
+		vars, err := ctx.LinkVariables(pkg)
+		if err != nil {
+			// Problems with packages referenced in the manifest are reported below
+			if len(v.Packages) > 0 {
+				util.Stderr(fmt.Sprintf("%v\n\n", err))
+			}
+			continue
+		}
+
+		found := []*context.LinkVariable{}
+		for _, item := range vars {
+			if item.Linkable || v.All {
+				found = append(found, item)
+			}
+		}
+
+		fmt.Print(util.Highlighter(pkg))
+		if len(found) == 0 {
+			fmt.Printf("  %s\n\n", dim("no linkable string variables"))
+			continue
+		}
+
+		width := 0
+		for _, item := range found {
+			if len(item.Target()) > width {
+				width = len(item.Target())
+			}
+		}
+
+		for _, item := range found {
+			detail := item.Location()
+			if len(item.Value) > 0 {
+				detail = fmt.Sprintf("%s (default %q)", detail, item.Value)
+			}
+
+			status := ""
+			if source, exists := configured[item.Target()]; exists {
+				if item.Linkable {
+					status = green(" ← " + source)
+				} else {
+					status = red(" ← " + source)
+				}
+			}
+
+			if !item.Linkable {
+				detail = fmt.Sprintf("%s, cannot be set: %s", detail, item.Reason)
+			}
+
+			fmt.Printf("  %-*s  %s%s\n", width, item.Target(), dim(detail), status)
+		}
+
+		fmt.Println("")
+	}
+
+	for _, problem := range ctx.CheckVariables() {
+		util.Stderr(fmt.Sprintf("warning: %s\n", problem))
+	}
+
+	return nil
+}
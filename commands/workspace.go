@@ -0,0 +1,371 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/quikdev/go/config"
+	"github.com/quikdev/go/util"
+)
+
+// workspaceModule is a module of a go.work workspace.
+type workspaceModule struct {
+	Dir      string   // Absolute path
+	Label    string   // Path relative to the workspace root
+	Path     string   // Module path (from go.mod)
+	Manifest bool     // The module has a manifest
+	Deps     []string // Module paths of the workspace modules this module imports
+}
+
+// workspaceResult is the outcome of running a command in a module.
+type workspaceResult struct {
+	Status   string // ok, failed, or skipped
+	Reason   string
+	Duration time.Duration
+}
+
+// workspaceModules discovers the modules of the go.work workspace containing
+// the current directory, along with the dependencies between them.
+func workspaceModules() (string, []*workspaceModule, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", []*workspaceModule{}, err
+	}
+
+	work, exists := util.FindGoWorkFile(wd)
+	if !exists {
+		return "", []*workspaceModule{}, fmt.Errorf("go.work not found (--all requires a Go workspace)")
+	}
+	root := filepath.Dir(work)
+
+	cmd := exec.Command("go", "work", "edit", "-json", work)
+	out, err := cmd.Output()
+	if err != nil {
+		return root, []*workspaceModule{}, fmt.Errorf("cannot read %s: %v", work, err)
+	}
+
+	var workfile struct {
+		Use []struct{ DiskPath string }
+	}
+	if err := json.Unmarshal(out, &workfile); err != nil {
+		return root, []*workspaceModule{}, err
+	}
+
+	modules := []*workspaceModule{}
+	for _, use := range workfile.Use {
+		dir := use.DiskPath
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		dir = filepath.Clean(dir)
+
+		content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return root, modules, err
+		}
+
+		label, err := filepath.Rel(root, dir)
+		if err != nil {
+			label = dir
+		}
+
+		module := &workspaceModule{
+			Dir:   dir,
+			Label: filepath.ToSlash(label),
+			Path:  moduleName(string(content)),
+		}
+
+		_, module.Manifest = config.ManifestIn(dir)
+
+		modules = append(modules, module)
+	}
+
+	for _, module := range modules {
+		module.Deps = moduleDependencies(module, modules)
+	}
+
+	return root, modules, nil
+}
+
+// moduleName returns the module path declared in go.mod content.
+func moduleName(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(line[len("module "):]), `"`)
+		}
+	}
+
+	return ""
+}
+
+// moduleDependencies identifies the workspace modules imported by a module.
+func moduleDependencies(module *workspaceModule, modules []*workspaceModule) []string {
+	deps := []string{}
+
+	filepath.Walk(module.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			name := info.Name()
+			if path != module.Dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata" || util.FileExists(filepath.Join(path, "go.mod"))) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+
+		for _, imp := range file.Imports {
+			importpath := strings.Trim(imp.Path.Value, `"`)
+
+			// The longest matching module path provides the package
+			var provider *workspaceModule
+			for _, candidate := range modules {
+				if len(candidate.Path) > 0 && (importpath == candidate.Path || strings.HasPrefix(importpath, candidate.Path+"/")) {
+					if provider == nil || len(candidate.Path) > len(provider.Path) {
+						provider = candidate
+					}
+				}
+			}
+
+			if provider != nil && provider != module && !util.InSlice[string](provider.Path, deps) {
+				deps = append(deps, provider.Path)
+			}
+		}
+
+		return nil
+	})
+
+	sort.Strings(deps)
+
+	return deps
+}
+
+// orderModules sorts modules so dependencies come before the modules that
+// import them (modules without a dependency relationship are sorted by label).
+func orderModules(modules []*workspaceModule) ([]*workspaceModule, error) {
+	bypath := map[string]*workspaceModule{}
+	for _, module := range modules {
+		bypath[module.Path] = module
+	}
+
+	sorted := append([]*workspaceModule{}, modules...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Label < sorted[j].Label
+	})
+
+	result := []*workspaceModule{}
+	state := map[string]int{} // 1 = visiting, 2 = visited
+
+	var visit func(module *workspaceModule, chain []string) error
+	visit = func(module *workspaceModule, chain []string) error {
+		switch state[module.Path] {
+		case 1:
+			return fmt.Errorf("circular module dependency: %s", strings.Join(append(chain, module.Label), " → "))
+		case 2:
+			return nil
+		}
+
+		state[module.Path] = 1
+		for _, dep := range module.Deps {
+			if err := visit(bypath[dep], append(chain, module.Label)); err != nil {
+				return err
+			}
+		}
+		state[module.Path] = 2
+		result = append(result, module)
+
+		return nil
+	}
+
+	for _, module := range sorted {
+		if err := visit(module, []string{}); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// workspaceArgs returns the command line arguments for running the command
+// in each module (the --all, --jobs, and --manifest flags are removed).
+func workspaceArgs() []string {
+	args := []string{}
+	skip := false
+	for _, arg := range os.Args[1:] {
+		if skip {
+			skip = false
+			continue
+		}
+
+		switch {
+		case arg == "--all":
+			continue
+		case arg == "--jobs" || arg == "-j" || arg == "--manifest":
+			skip = true
+			continue
+		case strings.HasPrefix(arg, "--jobs=") || strings.HasPrefix(arg, "--manifest="):
+			continue
+		}
+
+		args = append(args, arg)
+	}
+
+	return args
+}
+
+// runWorkspace runs a qgo command in every module of the workspace. Modules
+// run in parallel (up to the number of jobs), but never before the workspace
+// modules they depend on. When a module fails, the modules that depend on it
+// are skipped.
+func runWorkspace(jobs int, manifestOnly bool) error {
+	root, modules, err := workspaceModules()
+	if err != nil {
+		util.Stderr(err, true)
+	}
+
+	modules, err = orderModules(modules)
+	if err != nil {
+		util.Stderr(err, true)
+	}
+
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+
+	exe, err := os.Executable()
+	util.BailOnError(err)
+
+	args := workspaceArgs()
+
+	width := 0
+	for _, module := range modules {
+		if len(module.Label) > width {
+			width = len(module.Label)
+		}
+	}
+
+	util.Stdout(fmt.Sprintf("\n# running \"qgo %s\" in %d workspace modules (%s)\n\n", strings.Join(args, " "), len(modules), root))
+
+	results := map[string]*workspaceResult{}
+	done := map[string]chan struct{}{}
+	for _, module := range modules {
+		done[module.Path] = make(chan struct{})
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, jobs)
+
+	for i, module := range modules {
+		wg.Add(1)
+		go func(module *workspaceModule, prefix string) {
+			defer wg.Done()
+			defer close(done[module.Path])
+
+			result := &workspaceResult{Status: "ok"}
+			defer func() {
+				mu.Lock()
+				results[module.Path] = result
+				mu.Unlock()
+			}()
+
+			for _, dep := range module.Deps {
+				<-done[dep]
+				mu.Lock()
+				depresult := results[dep]
+				mu.Unlock()
+				if depresult.Status == "failed" || (depresult.Status == "skipped" && depresult.Reason != "no manifest") {
+					result.Status = "skipped"
+					result.Reason = "dependency failed"
+					return
+				}
+			}
+
+			if manifestOnly && !module.Manifest {
+				result.Status = "skipped"
+				result.Reason = "no manifest"
+				return
+			}
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			cmd := exec.Command(exe, args...)
+			cmd.Dir = module.Dir
+			cmd.Env = append(os.Environ(), "QGO_MANIFEST=")
+
+			output := newPrefixWriter(prefix, &mu)
+			cmd.Stdout = output
+			cmd.Stderr = output
+
+			err := cmd.Run()
+			output.Close()
+
+			result.Duration = time.Since(start)
+			if err != nil {
+				result.Status = "failed"
+				result.Reason = err.Error()
+			}
+		}(module, prefixFor(module.Label, width, i))
+	}
+
+	wg.Wait()
+
+	// Summary
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	dim := color.New(color.Faint).SprintFunc()
+
+	failed := 0
+	fmt.Println("")
+	util.Stdout("# workspace summary\n\n")
+	for _, module := range modules {
+		result := results[module.Path]
+		status := ""
+		switch result.Status {
+		case "ok":
+			status = green("✓ ok") + dim(fmt.Sprintf(" (%s)", result.Duration.Round(time.Millisecond)))
+		case "failed":
+			failed++
+			status = red("✗ failed") + dim(fmt.Sprintf(" (%s, %s)", result.Reason, result.Duration.Round(time.Millisecond)))
+		default:
+			status = dim("- skipped (" + result.Reason + ")")
+		}
+
+		fmt.Printf("  %-*s  %s\n", width, module.Label, status)
+	}
+	fmt.Println("")
+
+	if failed > 0 {
+		plural := ""
+		if failed != 1 {
+			plural = "s"
+		}
+		util.Stderr(fmt.Sprintf("%d module%s failed\n", failed, plural), true)
+	}
+
+	return nil
+}
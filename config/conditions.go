@@ -0,0 +1,197 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/quikdev/go/util"
+)
+
+// automaticProfiles identifies the profiles that apply to the current
+// environment, along with the reason each one applies:
+//
+//	<os>, <arch>, <os>/<arch>  matches runtime.GOOS/runtime.GOARCH (ex: linux/arm64)
+//	ci                         the CI environment variable is true
+//	"when": {...}              all of the conditions are satisfied (other
+//	                           profiles may apply in CI with "when": {"ci": true})
+func automaticProfiles(definitions map[string]interface{}) ([]string, map[string]string, error) {
+	names := []string{}
+	reasons := make(map[string]string)
+
+	add := func(name string, reason string) {
+		if _, exists := definitions[name]; exists && !util.InSlice[string](name, names) {
+			names = append(names, name)
+			reasons[name] = reason
+		}
+	}
+
+	goos := strings.ToLower(runtime.GOOS)
+	goarch := strings.ToLower(runtime.GOARCH)
+	add(goos, "GOOS="+goos)
+	add(goarch, "GOARCH="+goarch)
+	add(goos+"/"+goarch, "GOOS="+goos+" GOARCH="+goarch)
+
+	if util.IsCI() {
+		add("ci", "CI="+os.Getenv("CI"))
+	}
+
+	conditional := []string{}
+	for name, definition := range definitions {
+		if profile, ok := definition.(map[string]interface{}); ok {
+			if _, exists := profile["when"]; exists {
+				conditional = append(conditional, name)
+			}
+		}
+	}
+	sort.Strings(conditional)
+
+	for _, name := range conditional {
+		when, ok := definitions[name].(map[string]interface{})["when"].(map[string]interface{})
+		if !ok {
+			return names, reasons, fmt.Errorf(`invalid "when" condition in the "%s" profile (expected an object)`, name)
+		}
+
+		reason, matched, err := satisfies(when)
+		if err != nil {
+			return names, reasons, fmt.Errorf(`invalid "when" condition in the "%s" profile: %v`, name, err)
+		}
+
+		if matched {
+			add(name, reason)
+		}
+	}
+
+	return names, reasons, nil
+}
+
+// The current git branch is only identified when a condition requires it.
+var branch *string
+
+// satisfies determines whether all of the conditions are met. Each condition
+// may be a single value or a list of values (any of which may match). Values
+// support glob patterns (see glob).
+func satisfies(when map[string]interface{}) (string, bool, error) {
+	keys := make([]string, 0, len(when))
+	for key := range when {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	reasons := []string{}
+	for _, key := range keys {
+		values := []string{}
+		switch value := when[key].(type) {
+		case string:
+			values = append(values, value)
+		case bool:
+			values = append(values, fmt.Sprintf("%v", value))
+		case []interface{}:
+			for _, item := range value {
+				values = append(values, fmt.Sprintf("%v", item))
+			}
+		default:
+			return "", false, fmt.Errorf(`unsupported value for "%s"`, key)
+		}
+
+		var actual func(pattern string) (string, bool)
+		switch key {
+		case "env":
+			actual = func(pattern string) (string, bool) {
+				name, expected, hasvalue := strings.Cut(pattern, "=")
+				value, exists := os.LookupEnv(name)
+				if !hasvalue {
+					return name, exists && len(value) > 0
+				}
+				return name + "=" + value, glob(expected, value)
+			}
+		case "branch":
+			if branch == nil {
+				current, _ := util.GetGitBranch()
+				branch = &current
+			}
+			actual = func(pattern string) (string, bool) {
+				return "branch " + *branch, len(*branch) > 0 && glob(pattern, *branch)
+			}
+		case "os":
+			actual = func(pattern string) (string, bool) {
+				return "GOOS=" + runtime.GOOS, glob(pattern, runtime.GOOS)
+			}
+		case "arch":
+			actual = func(pattern string) (string, bool) {
+				return "GOARCH=" + runtime.GOARCH, glob(pattern, runtime.GOARCH)
+			}
+		case "ci":
+			actual = func(pattern string) (string, bool) {
+				return fmt.Sprintf("CI=%v", util.IsCI()), pattern == fmt.Sprintf("%v", util.IsCI())
+			}
+		default:
+			return "", false, fmt.Errorf(`unknown condition "%s" (expected env, branch, os, arch, or ci)`, key)
+		}
+
+		matched := false
+		for _, pattern := range values {
+			if reason, ok := actual(pattern); ok {
+				reasons = append(reasons, reason)
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return "", false, nil
+		}
+	}
+
+	return strings.Join(reasons, ", "), true, nil
+}
+
+// glob matches a value against a glob pattern. "*" and "?" do not match "/"
+// (like path.Match), while "**" matches any sequence of characters, including
+// "/" (ex: "feature/*" matches feature/x, "feature/**" also matches
+// feature/x/y). Character classes ("[a-z]", "[!0-9]") and "\" escapes are
+// supported.
+func glob(pattern string, value string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return false
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	expr.WriteString("$")
+
+	matched, err := regexp.MatchString(expr.String(), value)
+	return err == nil && matched
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		matched bool
+	}{
+		{"main", "main", true},
+		{"main", "master", false},
+		{"release/*", "release/1.0", true},
+		{"release/*", "release/1.0/hotfix", false},
+		{"release/**", "release/1.0/hotfix", true},
+		{"feature/*", "feature/x", true},
+		{"*", "feature/x", false},
+		{"**", "feature/x", true},
+		{"**/x", "feature/team/x", true},
+		{"v?.0", "v1.0", true},
+		{"v?.0", "v/.0", false},
+		{"v[0-9].*", "v2.1", true},
+		{"v[!0-9].*", "v2.1", false},
+		{"a.b", "axb", false},
+		{"\\*", "*", true},
+		{"\\*", "x", false},
+		{"[", "[", false},
+		{"staging", "STAGING", false},
+	}
+
+	for _, test := range tests {
+		if matched := glob(test.pattern, test.value); matched != test.matched {
+			t.Errorf("glob(%q, %q) = %v, expected %v", test.pattern, test.value, matched, test.matched)
+		}
+	}
+}
+
+func TestAutomaticProfilesCI(t *testing.T) {
+	definitions := map[string]interface{}{
+		"ci":       map[string]interface{}{},
+		"pipeline": map[string]interface{}{"when": map[string]interface{}{"ci": true}},
+		"local":    map[string]interface{}{"when": map[string]interface{}{"ci": false}},
+	}
+
+	tests := []struct {
+		ci       string
+		expected []string
+		reasons  map[string]string
+	}{
+		{"true", []string{"ci", "pipeline"}, map[string]string{"ci": "CI=true", "pipeline": "CI=true"}},
+		{"1", []string{"ci", "pipeline"}, map[string]string{"ci": "CI=1", "pipeline": "CI=true"}},
+		{"", []string{"local"}, map[string]string{"local": "CI=false"}},
+		{"false", []string{"local"}, map[string]string{"local": "CI=false"}},
+	}
+
+	for _, test := range tests {
+		t.Run("CI="+test.ci, func(t *testing.T) {
+			t.Setenv("CI", test.ci)
+
+			names, reasons, err := automaticProfiles(definitions)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, names)
+			}
+			if !reflect.DeepEqual(reasons, test.reasons) {
+				t.Errorf("expected reasons %v, got %v", test.reasons, reasons)
+			}
+		})
+	}
+}
@@ -1,37 +1,47 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
-	"runtime"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
-	"github.com/peterbourgon/mergemap"
 	"github.com/quikdev/go/util"
 )
 
 type Config struct {
-	data    map[string]interface{}
-	cfgfile string
-	exists  bool
+	data     map[string]interface{}
+	sources  map[string]string
+	profiles []string
+	cfgfile  string
+	exists   bool
+	resolver Resolver
 }
 
 var jsonfiles = []string{"manifest"}
 var warned = false
 var warnedprofiles = false
 
+// The location of the manifest, relative to the directory qgo was launched in.
+var location string
+
 func New(profiles ...string) *Config {
-	cfgfile := "manifest.json"
+	cfgfile := findManifest()
 	exists := false
-	data, err := readJSON(cfgfile)
+	explicit := []string{}
+	sources := make(map[string]string)
+	data, err := readManifest(cfgfile, sources)
 	if err != nil {
 		var emptystr string
 		cfgfile = emptystr
 	} else {
 		exists = true
 
+		// Identifies why a profile was applied (when not explicitly requested)
+		reasons := make(map[string]string)
+
 		// If no profiles are specified and the default_profile exists in the
 		// manifest, apply it.
 		if len(profiles) == 0 {
@@ -39,15 +49,28 @@ func New(profiles ...string) *Config {
 				defaults := p.([]interface{})
 				for _, value := range defaults {
 					profiles = append(profiles, value.(string))
+					reasons[value.(string)] = "default"
 				}
 			}
 		}
 
-		// Apply OS-level profiles
+		// Profiles requested explicitly (--profile or default_profiles)
+		requested := append([]string{}, profiles...)
+
+		// Apply profiles matching the environment (OS, architecture, CI, or "when" conditions)
 		if prof, exists := data["profile"]; exists {
-			os := strings.ToLower(runtime.GOOS)
-			if _, exists := prof.(map[string]interface{})[os]; exists {
-				profiles = append(profiles, os)
+			if definitions, ok := prof.(map[string]interface{}); ok {
+				names, why, err := automaticProfiles(definitions)
+				if err != nil {
+					util.Stderr(err, true)
+				}
+
+				for _, name := range names {
+					if !util.InSlice[string](name, profiles) {
+						profiles = append(profiles, name)
+						reasons[name] = why[name]
+					}
+				}
 			}
 		}
 
@@ -58,16 +81,54 @@ func New(profiles ...string) *Config {
 			used := []string{}
 			availableprofiles := []string{}
 			if profileData, exists := data["profile"]; exists {
-				for name, profile := range profileData.(map[string]interface{}) {
+				definitions := profileData.(map[string]interface{})
+				for name := range definitions {
 					availableprofiles = append(availableprofiles, name)
-					if util.IndexOf[string](profiles, name) >= 0 {
-						_, err := json.MarshalIndent(profile.(map[string]interface{}), "", "  ")
-						if err != nil {
-							util.Stderr(err, true)
+				}
+				sort.Strings(availableprofiles)
+
+				// Profiles are applied in the order they are specified. Profiles
+				// extended by a profile are applied before it (once).
+				applied := []string{}
+				for _, requested := range profiles {
+					if _, exists := definitions[requested]; !exists || util.InSlice[string](requested, used) {
+						continue
+					}
+
+					order, err := profileOrder(definitions, requested, []string{})
+					if err != nil {
+						util.Stderr(err, true)
+					}
+
+					for _, name := range order {
+						if util.InSlice[string](name, applied) {
+							continue
 						}
-						data = mergemap.Merge(data, profile.(map[string]interface{}))
-						used = append(used, name)
+
+						profile, ok := definitions[name].(map[string]interface{})
+						if !ok {
+							util.Stderr(fmt.Sprintf(`the "%s" profile must be an object`, name), true)
+						}
+
+						attributes := make(map[string]interface{}, len(profile))
+						for key, value := range profile {
+							if key != "extends" && key != "when" {
+								attributes[key] = value
+							}
+						}
+
+						track(sources, []string{}, attributes, "profile: "+name)
+						data = merge(data, attributes)
+						applied = append(applied, name)
 					}
+
+					used = append(used, requested)
+				}
+			}
+
+			for _, name := range requested {
+				if util.InSlice[string](name, used) {
+					explicit = append(explicit, name)
 				}
 			}
 
@@ -78,7 +139,7 @@ func New(profiles ...string) *Config {
 					if len(profiles) != 1 {
 						plural = "s"
 					}
-					util.Stderr(fmt.Sprintf(`%s profile%s not found (no profiles available in manifest.json)`, strings.Join(profiles, "/"), plural), true)
+					util.Stderr(fmt.Sprintf(`%s profile%s not found (no profiles available in %s)`, strings.Join(profiles, "/"), plural, cfgfile), true)
 				}
 
 				missing := []string{}
@@ -92,17 +153,30 @@ func New(profiles ...string) *Config {
 				if len(missing) != 1 {
 					plural = "s"
 				}
-				util.Stderr(fmt.Sprintf(`%s profile%s not found in manifest.json - please use one/more of the following: %s (or create the missing profile%s)`, strings.Join(profiles, "/"), plural, strings.Join(availableprofiles, ", "), plural), true)
+				util.Stderr(fmt.Sprintf(`%s profile%s not found in %s - please use one/more of the following: %s (or create the missing profile%s)`, strings.Join(profiles, "/"), plural, cfgfile, strings.Join(availableprofiles, ", "), plural), true)
 			}
 
-			if !warnedprofiles && len(profiles) > 0 && os.Args[1] != "exec" && os.Args[1] != "kill" {
+			if !warnedprofiles && len(profiles) > 0 && util.CurrentCommand() != "exec" && util.CurrentCommand() != "kill" {
 				magenta := color.New(color.FgMagenta, color.Faint, color.Italic).SprintFunc()
 				dim := color.New(color.Faint).SprintFunc()
 				plural := ""
-				if len(profiles) != 1 {
+				if len(used) != 1 {
 					plural = "s"
 				}
-				util.Stdout(fmt.Sprintf(" with %s"+dim(" profile%s applied\n"), magenta(strings.Join(used, "+")), plural))
+
+				why := []string{}
+				for _, name := range used {
+					if reason, exists := reasons[name]; exists {
+						why = append(why, name+": "+reason)
+					}
+				}
+
+				explanation := ""
+				if len(why) > 0 {
+					explanation = " (" + strings.Join(why, ", ") + ")"
+				}
+
+				util.Stdout(fmt.Sprintf(" with %s"+dim(" profile%s applied%s\n"), magenta(strings.Join(used, "+")), plural, explanation))
 				warnedprofiles = true
 			}
 		}
@@ -114,15 +188,78 @@ func New(profiles ...string) *Config {
 	// Adds an extra break after manifest notification
 	fmt.Println("")
 
-	return &Config{data: data, cfgfile: cfgfile, exists: exists}
+	return &Config{data: data, sources: sources, profiles: explicit, cfgfile: cfgfile, exists: exists}
+}
+
+// findManifest identifies the manifest file. An explicit manifest (--manifest
+// flag or QGO_MANIFEST environment variable) takes precedence. Otherwise the
+// current directory and its parents are searched, stopping at the module root
+// (the directory containing go.mod). When the manifest is not in the current
+// directory, its directory becomes the working directory so relative paths
+// in the manifest resolve the same way they do from the project root.
+func findManifest() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return manifestfiles[0]
+	}
+
+	if file := os.Getenv("QGO_MANIFEST"); file != "" {
+		if !util.FileExists(file) {
+			util.Stderr(fmt.Sprintf("manifest not found: %s\n", file), true)
+		}
+
+		abs, err := filepath.Abs(file)
+		util.BailOnError(err)
+
+		location = file
+		if filepath.Dir(abs) != wd {
+			util.BailOnError(os.Chdir(filepath.Dir(abs)))
+		}
+
+		return filepath.Base(abs)
+	}
+
+	root, exists := util.FindModuleRoot(wd)
+	if !exists {
+		root = wd
+	}
+
+	dir := wd
+	for {
+		for _, name := range manifestfiles {
+			file := filepath.Join(dir, name)
+			if util.FileExists(file) {
+				if dir == wd {
+					location = name
+					return name
+				}
+
+				location, err = filepath.Rel(wd, file)
+				if err != nil {
+					location = file
+				}
+
+				util.BailOnError(os.Chdir(dir))
+				return name
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if dir == root || parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return manifestfiles[0]
 }
 
-func readJSON(file string) (map[string]interface{}, error) {
+func readManifest(file string, sources ...map[string]string) (map[string]interface{}, error) {
 	data := make(map[string]interface{})
 
 	fileContents, err := os.ReadFile(file)
 	if err != nil {
-		if strings.Contains(err.Error(), "cannot find the file") {
+		if os.IsNotExist(err) || strings.Contains(err.Error(), "cannot find the file") {
 			if !warned {
 				util.Stdout("\n# no manifest available\n\n")
 				warned = true
@@ -131,22 +268,29 @@ func readJSON(file string) (map[string]interface{}, error) {
 
 		return data, err
 	} else {
-		err = json.Unmarshal(fileContents, &data)
+		data, err = decode(file, fileContents)
 		if err != nil {
-			if syntaxErr, ok := err.(*json.SyntaxError); ok {
-				// Calculate the line number based on the byte offset
-				lineNumber := strings.Count(string(fileContents[:syntaxErr.Offset]), "\n") + 1
-				util.Stderr(fmt.Errorf("error reading manifest: %v\n  at %s:%d", err, file, lineNumber), true)
-			}
+			util.Stderr(err, true)
+		}
 
-			util.Stderr(fmt.Errorf("error reading manifest: %s", err.Error()), true)
-			// return data, err
+		// Apply inherited manifests
+		var src map[string]string
+		if len(sources) > 0 {
+			src = sources[0]
+		}
+		data, err = inherit(file, data, src, []string{})
+		if err != nil {
+			util.Stderr(err, true)
 		}
 
-		if !warned && os.Args[1] != "exec" {
+		if !warned && util.CurrentCommand() != "exec" {
 			magenta := color.New(color.FgMagenta, color.Faint).SprintFunc()
 			dim := color.New(color.Faint).SprintFunc()
-			fmt.Printf(dim("\n# using "+magenta("%s")+dim(" configuration")), file)
+			label := file
+			if location != "" {
+				label = location
+			}
+			fmt.Printf(dim("\n# using "+magenta("%s")+dim(" configuration")), label)
 			warned = true
 		}
 	}
@@ -160,7 +304,25 @@ func (cfg *Config) ManifestExists() bool {
 	return cfg.exists
 }
 
+// Get returns a manifest value (a dotted name identifies a nested value), with
+// ${...} references resolved. An unresolvable reference stops qgo with an error
+// identifying the attribute, so only commands reading the value are affected.
 func (cfg *Config) Get(name string) (interface{}, bool) {
+	value, exists := cfg.GetRaw(name)
+	if !exists {
+		return value, false
+	}
+
+	resolved, err := cfg.resolve(value, name)
+	if err != nil {
+		util.Stderr(err, true)
+	}
+
+	return resolved, true
+}
+
+// GetRaw returns a manifest value as it is written (references are not resolved).
+func (cfg *Config) GetRaw(name string) (interface{}, bool) {
 	if strings.Contains(name, ".") {
 		var data interface{}
 		data = cfg.data
@@ -197,9 +359,29 @@ func (cfg *Config) Raw() ([]byte, error) {
 	return os.ReadFile(cfg.cfgfile)
 }
 
+// GetEnvVars returns the environment variables qgo applies to the application
+// (see Environment). Variables inherited from the process are not included.
 func (cfg *Config) GetEnvVars() map[string]string {
+	result := make(map[string]string)
+
+	vars, err := cfg.Environment()
+	if err != nil {
+		util.Stderr(err, true)
+	}
+
+	for _, v := range vars {
+		if v.Source != "process" {
+			result[v.Name] = v.Value
+		}
+	}
+
+	return result
+}
+
+// GetInlineEnvVars returns the variables of the "env" manifest attribute.
+func (cfg *Config) GetInlineEnvVars() map[string]string {
 	if cfg.data == nil {
-		data, _ := readJSON(cfg.cfgfile)
+		data, _ := readManifest(cfg.cfgfile)
 		cfg.data = data
 	}
 
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// EnvVar is an environment variable applied to the application.
+type EnvVar struct {
+	Name   string
+	Value  string
+	Source string // The manifest/profile, .env file, or "process" (inherited)
+	Secret bool
+}
+
+// Masked returns the value, redacted if it is a secret.
+func (v *EnvVar) Masked() string {
+	if v.Secret && len(v.Value) > 0 {
+		return "********"
+	}
+
+	return v.Value
+}
+
+// Names that identify a secret (case-insensitive), in addition to those
+// listed in the "secrets" manifest attribute.
+var secretpatterns = []string{"*SECRET*", "*TOKEN*", "*PASSWORD*", "*PASSWD*", "*PRIVATE*", "*CREDENTIAL*", "*API_KEY*", "*APIKEY*", "*ACCESS_KEY*"}
+
+// Environment returns the effective environment variables of the application,
+// sorted by name. Values are applied in the following order of precedence:
+//
+//  1. the "env" manifest attribute (including profiles)
+//  2. the process environment
+//  3. the "env_files" (later files override earlier files)
+//
+// Variables inherited from the process are only included when they override
+// a value from an env file.
+func (cfg *Config) Environment() ([]*EnvVar, error) {
+	vars := map[string]*EnvVar{}
+
+	if files, exists := cfg.Get("env_files"); exists {
+		list := []string{}
+		switch value := files.(type) {
+		case string:
+			list = append(list, value)
+		case []interface{}:
+			for _, item := range value {
+				if file, ok := item.(string); ok {
+					list = append(list, file)
+				}
+			}
+		default:
+			return []*EnvVar{}, fmt.Errorf(`invalid "env_files" value (expected a file path or list of file paths)`)
+		}
+
+		for _, file := range list {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				// Optional files (ex: .env.local) may not exist
+				if os.IsNotExist(err) {
+					continue
+				}
+				return []*EnvVar{}, err
+			}
+
+			values, order, err := ParseDotenv(content)
+			if err != nil {
+				return []*EnvVar{}, fmt.Errorf("%s: %v", file, err)
+			}
+
+			for _, name := range order {
+				vars[name] = &EnvVar{Name: name, Value: values[name], Source: file}
+			}
+		}
+
+		for name, v := range vars {
+			if value, exists := os.LookupEnv(name); exists {
+				v.Value = value
+				v.Source = "process"
+			}
+		}
+	}
+
+	for name, value := range cfg.GetInlineEnvVars() {
+		source, exists := cfg.Source("env", name)
+		if !exists {
+			source = "manifest"
+		}
+		vars[name] = &EnvVar{Name: name, Value: value, Source: source}
+	}
+
+	secrets := []string{}
+	if list, exists := cfg.Get("secrets"); exists {
+		if items, ok := list.([]interface{}); ok {
+			for _, item := range items {
+				if name, ok := item.(string); ok {
+					secrets = append(secrets, name)
+				}
+			}
+		}
+	}
+
+	result := make([]*EnvVar, 0, len(vars))
+	for _, v := range vars {
+		v.Secret = isSecret(v.Name, secrets)
+		result = append(result, v)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// isSecret determines whether a variable name identifies a secret value.
+func isSecret(name string, secrets []string) bool {
+	for _, pattern := range secrets {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	upper := strings.ToUpper(name)
+	for _, pattern := range secretpatterns {
+		if matched, _ := path.Match(pattern, upper); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseDotenv parses the content of a .env file, returning the values and
+// the order the names were defined in. The following syntax is supported:
+//
+//	# comment
+//	NAME=value # comment
+//	export NAME=value
+//	NAME="double quoted\nwith escapes (may span multiple lines)"
+//	NAME='single quoted (literal)'
+func ParseDotenv(content []byte) (map[string]string, []string, error) {
+	values := map[string]string{}
+	order := []string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		text = strings.TrimPrefix(text, "export ")
+
+		name, value, found := strings.Cut(text, "=")
+		name = strings.TrimSpace(name)
+		if !found || len(name) == 0 || strings.ContainsAny(name, " \t") {
+			return values, order, fmt.Errorf("invalid line %d (expected NAME=value)", line)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, `"`):
+			// Double quoted values may span multiple lines
+			start := line
+			value = value[1:]
+			for !hasClosingQuote(value) {
+				if !scanner.Scan() {
+					return values, order, fmt.Errorf("unterminated quoted value on line %d", start)
+				}
+				line++
+				value += "\n" + scanner.Text()
+			}
+
+			end := closingQuote(value)
+			value = unescape(value[:end])
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return values, order, fmt.Errorf("unterminated quoted value on line %d", line)
+			}
+			value = value[1 : end+1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+
+		if _, exists := values[name]; !exists {
+			order = append(order, name)
+		}
+		values[name] = value
+	}
+
+	return values, order, scanner.Err()
+}
+
+// closingQuote returns the index of the first unescaped double quote.
+func closingQuote(value string) int {
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return -1
+}
+
+func hasClosingQuote(value string) bool {
+	return closingQuote(value) >= 0
+}
+
+func unescape(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+	return replacer.Replace(value)
+}
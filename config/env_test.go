@@ -0,0 +1,207 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+		order    []string
+	}{
+		{
+			name:     "plain values",
+			input:    "A=1\nB = two \n",
+			expected: map[string]string{"A": "1", "B": "two"},
+			order:    []string{"A", "B"},
+		},
+		{
+			name:     "comments and blank lines",
+			input:    "# comment\n\n  # indented comment\nA=1 # trailing comment\nB=a#b\n",
+			expected: map[string]string{"A": "1", "B": "a#b"},
+			order:    []string{"A", "B"},
+		},
+		{
+			name:     "export prefix",
+			input:    "export A=1\nexport  B=2\nexported=3\n",
+			expected: map[string]string{"A": "1", "B": "2", "exported": "3"},
+			order:    []string{"A", "B", "exported"},
+		},
+		{
+			name:     "empty values",
+			input:    "A=\nB=''\nC=\"\"\n",
+			expected: map[string]string{"A": "", "B": "", "C": ""},
+			order:    []string{"A", "B", "C"},
+		},
+		{
+			name:     "values containing equal signs",
+			input:    "URL=postgres://host/db?sslmode=disable\n",
+			expected: map[string]string{"URL": "postgres://host/db?sslmode=disable"},
+			order:    []string{"URL"},
+		},
+		{
+			name:     "double quotes",
+			input:    `A="a # not a comment" # comment` + "\n" + `B="say \"hi\""`,
+			expected: map[string]string{"A": "a # not a comment", "B": `say "hi"`},
+			order:    []string{"A", "B"},
+		},
+		{
+			name:     "double quoted escapes",
+			input:    `A="line1\nline2\ttab\\n"`,
+			expected: map[string]string{"A": "line1\nline2\ttab\\n"},
+			order:    []string{"A"},
+		},
+		{
+			name:     "single quotes are literal",
+			input:    `A='$HOME\n "quoted" # not a comment'`,
+			expected: map[string]string{"A": `$HOME\n "quoted" # not a comment`},
+			order:    []string{"A"},
+		},
+		{
+			name:     "multiline double quotes",
+			input:    "KEY=\"-----BEGIN-----\nabc\n-----END-----\"\nNEXT=1\n",
+			expected: map[string]string{"KEY": "-----BEGIN-----\nabc\n-----END-----", "NEXT": "1"},
+			order:    []string{"KEY", "NEXT"},
+		},
+		{
+			name:     "later values override earlier values",
+			input:    "A=1\nB=2\nA=3\n",
+			expected: map[string]string{"A": "3", "B": "2"},
+			order:    []string{"A", "B"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, order, err := ParseDotenv([]byte(test.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(values, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, values)
+			}
+			if !reflect.DeepEqual(order, test.order) {
+				t.Errorf("expected order %v, got %v", test.order, order)
+			}
+		})
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"A=1\nnot a variable\n", "invalid line 2"},
+		{"=value\n", "invalid line 1"},
+		{"MY VAR=1\n", "invalid line 1"},
+		{"A=1\nB=\"unterminated\nC=3\n", "unterminated quoted value on line 2"},
+		{"A='unterminated\n", "unterminated quoted value on line 1"},
+	}
+
+	for _, test := range tests {
+		_, _, err := ParseDotenv([]byte(test.input))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("ParseDotenv(%q): expected error %q, got %v", test.input, test.err, err)
+		}
+	}
+}
+
+func TestIsSecret(t *testing.T) {
+	secrets := []string{"STRIPE_*", "DATABASE_URL"}
+
+	tests := []struct {
+		name   string
+		secret bool
+	}{
+		{"STRIPE_KEY", true},
+		{"DATABASE_URL", true},
+		{"DATABASE_HOST", false},
+		{"GITHUB_TOKEN", true},
+		{"github_token", true},
+		{"ClientSecret", true},
+		{"DB_PASSWORD", true},
+		{"DB_PASSWD", true},
+		{"SSH_PRIVATE_KEY", true},
+		{"AWS_ACCESS_KEY_ID", true},
+		{"OPENAI_API_KEY", true},
+		{"APIKEY", true},
+		{"GOOGLE_CREDENTIALS", true},
+		{"PORT", false},
+		{"HOME", false},
+		{"KEY", false},
+		{"stripe_key", false}, // "secrets" patterns are case-sensitive
+	}
+
+	for _, test := range tests {
+		if secret := isSecret(test.name, secrets); secret != test.secret {
+			t.Errorf("isSecret(%q) = %v, expected %v", test.name, secret, test.secret)
+		}
+	}
+}
+
+func TestMasked(t *testing.T) {
+	tests := []struct {
+		v        EnvVar
+		expected string
+	}{
+		{EnvVar{Name: "PORT", Value: "8080"}, "8080"},
+		{EnvVar{Name: "TOKEN", Value: "abc", Secret: true}, "********"},
+		{EnvVar{Name: "TOKEN", Value: "", Secret: true}, ""},
+	}
+
+	for _, test := range tests {
+		if masked := test.v.Masked(); masked != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.v.Name, test.expected, masked)
+		}
+	}
+}
+
+// The manifest overrides the process environment, which overrides env files
+// (later files override earlier files).
+func TestEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	env := filepath.Join(dir, ".env")
+	local := filepath.Join(dir, ".env.local")
+	if err := os.WriteFile(env, []byte("A=file\nB=file\nD=file\nAPI_TOKEN=abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, []byte("B=local\nD=local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("A", "process")
+
+	cfg := &Config{data: map[string]interface{}{
+		"env":       map[string]interface{}{"C": "manifest", "B": "manifest"},
+		"env_files": []interface{}{env, local, filepath.Join(dir, ".env.missing")},
+		"secrets":   []interface{}{"C"},
+	}}
+
+	vars, err := cfg.Environment()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := map[string][2]string{}
+	for _, v := range vars {
+		result[v.Name] = [2]string{v.Value, v.Masked()}
+	}
+
+	expected := map[string][2]string{
+		"A":         {"process", "process"},
+		"B":         {"manifest", "manifest"},
+		"D":         {"local", "local"},
+		"C":         {"manifest", "********"},
+		"API_TOKEN": {"abc", "********"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
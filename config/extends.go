@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/peterbourgon/mergemap"
+)
+
+// inherit applies the manifests listed in the "extends" attribute. Parent
+// manifests are deep-merged in order (see merge), then the manifest itself
+// is merged on top of the result. Paths are relative to the manifest that
+// extends them. The sources map records which file each value came from.
+func inherit(file string, data map[string]interface{}, sources map[string]string, chain []string) (map[string]interface{}, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return data, err
+	}
+
+	for _, item := range chain {
+		if item == abs {
+			cycle := []string{}
+			for _, link := range append(chain, abs) {
+				cycle = append(cycle, label(link))
+			}
+			return data, fmt.Errorf("circular manifest inheritance: %s", strings.Join(cycle, " → "))
+		}
+	}
+	chain = append(chain, abs)
+
+	parents := []string{}
+	if ext, exists := data["extends"]; exists {
+		switch value := ext.(type) {
+		case string:
+			parents = append(parents, value)
+		case []interface{}:
+			for _, item := range value {
+				if parent, ok := item.(string); ok {
+					parents = append(parents, parent)
+				} else {
+					return data, fmt.Errorf(`invalid "extends" value in %s (expected a file path)`, label(abs))
+				}
+			}
+		default:
+			return data, fmt.Errorf(`invalid "extends" value in %s (expected a file path or list of file paths)`, label(abs))
+		}
+	}
+	delete(data, "extends")
+
+	result := make(map[string]interface{})
+	for _, parent := range parents {
+		path := parent
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(abs), parent)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return data, fmt.Errorf("cannot extend %s (referenced in %s): %v", parent, label(abs), err)
+		}
+
+		parentdata, err := decode(path, content)
+		if err != nil {
+			return data, err
+		}
+
+		parentdata, err = inherit(path, parentdata, sources, chain)
+		if err != nil {
+			return data, err
+		}
+
+		result = combine(result, parentdata)
+	}
+
+	track(sources, []string{}, data, label(abs))
+
+	return combine(result, data), nil
+}
+
+// combine merges two manifests, applying merge directives. Profiles are
+// deep-merged as-is, because their directives are applied when the
+// profile itself is applied.
+func combine(dst, src map[string]interface{}) map[string]interface{} {
+	dstprofiles, _ := dst["profile"].(map[string]interface{})
+	srcprofiles, _ := src["profile"].(map[string]interface{})
+
+	attributes := make(map[string]interface{}, len(src))
+	for key, value := range src {
+		if key != "profile" {
+			attributes[key] = value
+		}
+	}
+
+	result := merge(dst, attributes)
+	delete(result, "profile")
+
+	if dstprofiles != nil || srcprofiles != nil {
+		profiles := make(map[string]interface{})
+		for name, profile := range dstprofiles {
+			profiles[name] = profile
+		}
+		result["profile"] = mergemap.Merge(profiles, srcprofiles)
+	}
+
+	return result
+}
+
+// track records the source of every leaf value in the data.
+func track(sources map[string]string, path []string, value interface{}, source string) {
+	if sources == nil {
+		return
+	}
+
+	if obj, ok := value.(map[string]interface{}); ok {
+		for key, item := range obj {
+			name, _ := directiveOf(key)
+			track(sources, append(append([]string{}, path...), name), item, source)
+		}
+		return
+	}
+
+	sources[strings.Join(path, pathSeparator)] = source
+}
+
+// label returns the path of a file relative to the working directory.
+func label(file string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return file
+	}
+
+	rel, err := filepath.Rel(wd, file)
+	if err != nil {
+		return file
+	}
+
+	return filepath.ToSlash(rel)
+}
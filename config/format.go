@@ -0,0 +1,150 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/quikdev/go/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported manifest file names, in order of precedence.
+var manifestfiles = []string{"manifest.json", "manifest.jsonc", "manifest.yaml", "manifest.yml", "manifest.toml"}
+
+// ManifestIn returns the manifest file in a directory, if one exists.
+func ManifestIn(dir string) (string, bool) {
+	for _, name := range manifestfiles {
+		file := filepath.Join(dir, name)
+		if util.FileExists(file) {
+			return file, true
+		}
+	}
+
+	return "", false
+}
+
+// decode parses the raw contents of a manifest file based on the file extension.
+// JSON manifests may contain comments and trailing commas (JSONC/JSON5 style).
+func decode(file string, content []byte) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		raw := make(map[string]interface{})
+		if err := yaml.Unmarshal(content, &raw); err != nil {
+			return data, fmt.Errorf("error reading manifest: %v\n  at %s", err, file)
+		}
+		return normalize(raw)
+	case ".toml":
+		raw := make(map[string]interface{})
+		if _, err := toml.Decode(string(content), &raw); err != nil {
+			var perr toml.ParseError
+			if errors.As(err, &perr) {
+				return data, fmt.Errorf("error reading manifest: %s\n  at %s:%d", perr.Message, file, perr.Position.Line)
+			}
+			return data, fmt.Errorf("error reading manifest: %v\n  at %s", err, file)
+		}
+		return normalize(raw)
+	default:
+		// Comments and trailing commas are replaced with whitespace,
+		// so byte offsets (and therefore line numbers) remain accurate.
+		err := json.Unmarshal(StripJSONC(content), &data)
+		if err != nil {
+			var offset int64 = -1
+			if syntaxErr, ok := err.(*json.SyntaxError); ok {
+				offset = syntaxErr.Offset
+			} else if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+				offset = typeErr.Offset
+			}
+
+			if offset >= 0 && offset <= int64(len(content)) {
+				// Calculate the line number based on the byte offset
+				lineNumber := strings.Count(string(content[:offset]), "\n") + 1
+				return data, fmt.Errorf("error reading manifest: %v\n  at %s:%d", err, file, lineNumber)
+			}
+
+			return data, fmt.Errorf("error reading manifest: %s", err.Error())
+		}
+	}
+
+	return data, nil
+}
+
+// normalize converts YAML/TOML data into the same types produced by
+// encoding/json (i.e. float64 numbers, map[string]interface{} objects),
+// which the rest of the application expects.
+func normalize(raw map[string]interface{}) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+
+	content, err := json.Marshal(raw)
+	if err != nil {
+		return data, err
+	}
+
+	err = json.Unmarshal(content, &data)
+	return data, err
+}
+
+// StripJSONC replaces line comments, block comments, and trailing commas
+// with whitespace. Newlines are retained, so the output has the same
+// byte offsets as the input.
+func StripJSONC(content []byte) []byte {
+	out := make([]byte, len(content))
+	copy(out, content)
+
+	instring := false
+	lastcomma := -1
+
+	for i := 0; i < len(out); i++ {
+		ch := out[i]
+
+		if instring {
+			if ch == '\\' {
+				i++
+			} else if ch == '"' {
+				instring = false
+			}
+			continue
+		}
+
+		switch {
+		case ch == '"':
+			instring = true
+			lastcomma = -1
+		case ch == '/' && i+1 < len(out) && out[i+1] == '/':
+			for i < len(out) && out[i] != '\n' {
+				out[i] = ' '
+				i++
+			}
+		case ch == '/' && i+1 < len(out) && out[i+1] == '*':
+			out[i], out[i+1] = ' ', ' '
+			i += 2
+			for i < len(out) && !(out[i] == '*' && i+1 < len(out) && out[i+1] == '/') {
+				if out[i] != '\n' && out[i] != '\r' {
+					out[i] = ' '
+				}
+				i++
+			}
+			if i < len(out) {
+				out[i], out[i+1] = ' ', ' '
+				i++
+			}
+		case ch == ',':
+			lastcomma = i
+		case ch == '}' || ch == ']':
+			if lastcomma >= 0 {
+				out[lastcomma] = ' '
+			}
+			lastcomma = -1
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+		default:
+			lastcomma = -1
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "plain json",
+			input:    `{"name": "app", "version": "1.0.0"}`,
+			expected: map[string]interface{}{"name": "app", "version": "1.0.0"},
+		},
+		{
+			name: "line comments",
+			input: `{
+  // The name of the app
+  "name": "app" // trailing comment
+}`,
+			expected: map[string]interface{}{"name": "app"},
+		},
+		{
+			name:     "block comments",
+			input:    "{/* first\n line */ \"name\": /* inline */ \"app\"}",
+			expected: map[string]interface{}{"name": "app"},
+		},
+		{
+			name:     "comment markers in strings",
+			input:    `{"url": "https://example.com/*path*/", "glob": "src//*.go"}`,
+			expected: map[string]interface{}{"url": "https://example.com/*path*/", "glob": "src//*.go"},
+		},
+		{
+			name:     "escaped quotes in strings",
+			input:    `{"quote": "say \"hi\" // not a comment", "after": true}`,
+			expected: map[string]interface{}{"quote": `say "hi" // not a comment`, "after": true},
+		},
+		{
+			name:     "trailing commas",
+			input:    `{"tags": ["a", "b",], "name": "app",}`,
+			expected: map[string]interface{}{"tags": []interface{}{"a", "b"}, "name": "app"},
+		},
+		{
+			name: "trailing comma followed by a comment",
+			input: `{
+  "name": "app", // the name
+}`,
+			expected: map[string]interface{}{"name": "app"},
+		},
+		{
+			name:     "commas in strings",
+			input:    `{"list": "a,}", "name": "app",}`,
+			expected: map[string]interface{}{"list": "a,}", "name": "app"},
+		},
+		{
+			name:  "nested objects",
+			input: `{"build": {"os": ["linux",], /* os */ "minify": true,},}`,
+			expected: map[string]interface{}{
+				"build": map[string]interface{}{"os": []interface{}{"linux"}, "minify": true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stripped := StripJSONC([]byte(test.input))
+			if len(stripped) != len(test.input) {
+				t.Fatalf("expected %d bytes, got %d", len(test.input), len(stripped))
+			}
+
+			data := map[string]interface{}{}
+			if err := json.Unmarshal(stripped, &data); err != nil {
+				t.Fatalf("unexpected error: %v\n%s", err, stripped)
+			}
+			if !reflect.DeepEqual(data, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, data)
+			}
+		})
+	}
+}
+
+func TestStripJSONCRetainsLines(t *testing.T) {
+	input := "{\n  /* a\n  b */\n  \"name\": \"app\",\n  // c\n}"
+	stripped := string(StripJSONC([]byte(input)))
+
+	for i := range input {
+		if (input[i] == '\n') != (stripped[i] == '\n') {
+			t.Fatalf("newline moved at offset %d:\n%s", i, stripped)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		file     string
+		input    string
+		expected map[string]interface{}
+	}{
+		{"manifest.jsonc", `{"name": "app", /* c */ "port": 8080,}`, map[string]interface{}{"name": "app", "port": float64(8080)}},
+		{"manifest.yaml", "name: app # comment\nport: 8080\ntags:\n  - a\n", map[string]interface{}{"name": "app", "port": float64(8080), "tags": []interface{}{"a"}}},
+		{"manifest.toml", "name = \"app\"\nport = 8080\n[build]\nminify = true\n", map[string]interface{}{"name": "app", "port": float64(8080), "build": map[string]interface{}{"minify": true}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			data, err := decode(test.file, []byte(test.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(data, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, data)
+			}
+		})
+	}
+}
+
+func TestDecodeErrorLine(t *testing.T) {
+	_, err := decode("manifest.json", []byte("{\n  // comment\n  \"name\": \"app\"\n  \"port\": 1\n}"))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if expected := "manifest.json:4"; !strings.HasSuffix(err.Error(), expected) {
+		t.Errorf("expected the error to end with %s, got %q", expected, err.Error())
+	}
+}
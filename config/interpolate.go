@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Resolver returns the value of a dynamic reference (ex: git.commit). The
+// second return value is false when the reference is not recognized.
+type Resolver func(ref string) (string, bool, error)
+
+// Matches ${source.name}, ${profile}, and escaped $${...} expressions.
+var expression = regexp.MustCompile(`\$?\$\{([^{}]+)\}`)
+
+// Nested references (ex: a value referencing a value that references another
+// value) are resolved up to this depth, which also stops circular references.
+const maxdepth = 10
+
+// Interpolate replaces ${...} expressions in the value:
+//
+//	${manifest.version}  any source supported in variables (env, git, time, etc)
+//	${env.PORT:-8080}    a default value, used when the result is empty
+//	${profile}           the profile(s) applied with --profile or default_profiles
+//	$${...}              escaped (the literal text ${...})
+//
+// Expressions without a source, such as ${HOME}, are left as-is so shell
+// syntax in scripts is unaffected. Without a resolver (see SetResolver), the
+// value is returned as-is.
+func (cfg *Config) Interpolate(value string) (string, error) {
+	return cfg.interpolate(value, 0)
+}
+
+// SetResolver sets the resolver of the references in manifest values, which
+// are interpolated when they are read (see Get).
+func (cfg *Config) SetResolver(resolver Resolver) {
+	cfg.resolver = resolver
+}
+
+func (cfg *Config) interpolate(value string, depth int) (string, error) {
+	if !strings.Contains(value, "${") || cfg.resolver == nil {
+		return value, nil
+	}
+
+	if depth > maxdepth {
+		return value, fmt.Errorf("circular reference in %s", value)
+	}
+
+	var err error
+	result := expression.ReplaceAllStringFunc(value, func(match string) string {
+		if err != nil {
+			return match
+		}
+
+		// Escaped
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		ref := match[2 : len(match)-1]
+		fallback := ""
+		hasfallback := false
+		if i := strings.Index(ref, ":-"); i >= 0 {
+			ref, fallback, hasfallback = ref[:i], ref[i+2:], true
+		}
+		ref = strings.TrimSpace(ref)
+
+		if ref != "profile" && !strings.Contains(ref, ".") {
+			return match
+		}
+
+		// Unset environment variables are undefined (unless there is a default)
+		if strings.HasPrefix(ref, "env.") {
+			if _, exists := os.LookupEnv(ref[4:]); !exists {
+				if hasfallback {
+					return fallback
+				}
+				err = fmt.Errorf("undefined reference %s (environment variable is not set)", match)
+				return match
+			}
+		}
+
+		resolved, found, rerr := cfg.resolver(ref)
+		if rerr != nil {
+			err = rerr
+			return match
+		}
+
+		if !found {
+			if hasfallback {
+				return fallback
+			}
+			err = fmt.Errorf("undefined reference %s", match)
+			return match
+		}
+
+		if len(resolved) == 0 && hasfallback {
+			return fallback
+		}
+
+		resolved, err = cfg.interpolate(resolved, depth+1)
+		return resolved
+	})
+
+	return result, err
+}
+
+// resolve returns a copy of a manifest value with every string interpolated.
+// path identifies the value in errors.
+func (cfg *Config) resolve(data interface{}, path string) (interface{}, error) {
+	switch value := data.(type) {
+	case string:
+		result, err := cfg.Interpolate(value)
+		if err != nil {
+			return value, fmt.Errorf(`%v (in "%s")`, err, path)
+		}
+		return result, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		result := make(map[string]interface{}, len(value))
+		for _, key := range keys {
+			name := key
+			if len(path) > 0 {
+				name = path + "." + key
+			}
+
+			item, err := cfg.resolve(value[key], name)
+			if err != nil {
+				return value, err
+			}
+			result[key] = item
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i := range value {
+			item, err := cfg.resolve(value[i], fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return value, err
+			}
+			result[i] = item
+		}
+		return result, nil
+	}
+
+	return data, nil
+}
+
+// Profiles returns the profiles that were explicitly applied (i.e. with
+// --profile or default_profiles), excluding automatically applied profiles.
+func (cfg *Config) Profiles() []string {
+	return cfg.profiles
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testResolver(ref string) (string, bool, error) {
+	switch ref {
+	case "git.commit":
+		return "abc123", true, nil
+	case "manifest.version":
+		return "${git.commit}", true, nil
+	case "manifest.loop":
+		return "${manifest.loop}", true, nil
+	case "env.EMPTY":
+		return "", true, nil
+	}
+	return ref, false, nil
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		value    string
+		expected string
+		err      string
+	}{
+		{value: "plain", expected: "plain"},
+		{value: "dist/${git.commit}/app", expected: "dist/abc123/app"},
+		{value: "${manifest.version}", expected: "abc123"},
+		{value: "${ git.commit }", expected: "abc123"},
+		{value: "$${git.commit}", expected: "${git.commit}"},
+		{value: "${HOME}", expected: "${HOME}"},
+		{value: "${env.EMPTY:-dev}", expected: "dev"},
+		{value: "${env.QGO_TEST_UNSET:-dev}", expected: "dev"},
+		{value: "${env.QGO_TEST_UNSET}", err: "environment variable is not set"},
+		{value: "${unknown.source}", err: "undefined reference ${unknown.source}"},
+		{value: "${unknown.source:-x}", expected: "x"},
+		{value: "${manifest.loop}", err: "circular reference"},
+	}
+
+	cfg := &Config{resolver: testResolver}
+	for _, test := range tests {
+		result, err := cfg.Interpolate(test.value)
+		if len(test.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Interpolate(%q): expected error %q, got %v", test.value, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Interpolate(%q): unexpected error: %v", test.value, err)
+		} else if result != test.expected {
+			t.Errorf("Interpolate(%q) = %q, expected %q", test.value, result, test.expected)
+		}
+	}
+}
+
+func TestInterpolateWithoutResolver(t *testing.T) {
+	cfg := &Config{}
+	if result, err := cfg.Interpolate("${git.commit}"); err != nil || result != "${git.commit}" {
+		t.Errorf("expected the value as-is, got %q (%v)", result, err)
+	}
+}
+
+func TestGetResolvesLazily(t *testing.T) {
+	data := map[string]interface{}{
+		"output": "dist/${git.commit}",
+		"build":  map[string]interface{}{"tags": []interface{}{"${git.commit}", "a"}},
+		"scripts": map[string]interface{}{
+			"deploy": "scp app ${env.QGO_TEST_UNSET}:~/",
+		},
+	}
+	cfg := &Config{data: data, resolver: testResolver}
+
+	if value, _ := cfg.Get("output"); value != "dist/abc123" {
+		t.Errorf("unexpected output %v", value)
+	}
+	if value, _ := cfg.Get("build.tags"); !reflect.DeepEqual(value, []interface{}{"abc123", "a"}) {
+		t.Errorf("unexpected tags %v", value)
+	}
+
+	// Values are not modified, and unresolvable values are only an error when read
+	if value, _ := cfg.GetRaw("output"); value != "dist/${git.commit}" {
+		t.Errorf("the manifest data was modified: %v", value)
+	}
+	if _, err := cfg.resolve(data["scripts"], "scripts"); err == nil || !strings.Contains(err.Error(), `(in "scripts.deploy")`) {
+		t.Errorf("expected an error identifying the attribute, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// merge deep-merges src into dst and returns the result. Attribute names in
+// src may end with a merge directive:
+//
+//	"key+": [...]  appends to the existing array (objects are merged)
+//	"key!": ...    replaces the existing value (objects are not merged)
+//	"key": null    removes the attribute
+//
+// Without a directive, objects are merged recursively and all other values
+// (including arrays) replace the existing value. Neither map is modified.
+func merge(dst, src map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(dst)+len(src))
+	for key, value := range dst {
+		result[key] = value
+	}
+
+	for key, value := range src {
+		name, directive := directiveOf(key)
+
+		if value == nil {
+			delete(result, name)
+			continue
+		}
+
+		existing, exists := result[name]
+
+		switch directive {
+		case "!":
+			result[name] = clean(value)
+		case "+":
+			if list, ok := existing.([]interface{}); ok {
+				if items, ok := value.([]interface{}); ok {
+					result[name] = append(append([]interface{}{}, list...), clean(items).([]interface{})...)
+				} else {
+					result[name] = append(append([]interface{}{}, list...), clean(value))
+				}
+				continue
+			}
+			fallthrough
+		default:
+			srcmap, srcok := value.(map[string]interface{})
+			dstmap, dstok := existing.(map[string]interface{})
+			if exists && srcok && dstok {
+				result[name] = merge(dstmap, srcmap)
+			} else {
+				result[name] = clean(value)
+			}
+		}
+	}
+
+	return result
+}
+
+// clean applies merge directives within a value that has nothing to merge
+// with (i.e. directives are removed from nested attribute names).
+func clean(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return merge(map[string]interface{}{}, v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = clean(item)
+		}
+		return list
+	}
+
+	return value
+}
+
+func directiveOf(key string) (string, string) {
+	if len(key) > 1 && (strings.HasSuffix(key, "+") || strings.HasSuffix(key, "!")) {
+		return key[:len(key)-1], key[len(key)-1:]
+	}
+
+	return key, ""
+}
+
+// profileOrder returns the named profile preceded by every profile it extends
+// (recursively), in the order they should be applied.
+func profileOrder(profiles map[string]interface{}, name string, chain []string) ([]string, error) {
+	for _, link := range chain {
+		if link == name {
+			return []string{}, fmt.Errorf("circular profile inheritance: %s", strings.Join(append(chain, name), " → "))
+		}
+	}
+	chain = append(chain, name)
+
+	profile, exists := profiles[name]
+	if !exists {
+		if len(chain) == 1 {
+			return []string{}, fmt.Errorf(`profile "%s" not found`, name)
+		}
+		return []string{}, fmt.Errorf(`profile "%s" not found (extended by the "%s" profile)`, name, chain[len(chain)-2])
+	}
+
+	parents := []string{}
+	if data, ok := profile.(map[string]interface{}); ok {
+		switch ext := data["extends"].(type) {
+		case string:
+			parents = append(parents, ext)
+		case []interface{}:
+			for _, item := range ext {
+				if parent, ok := item.(string); ok {
+					parents = append(parents, parent)
+				}
+			}
+		}
+	}
+
+	order := []string{}
+	for _, parent := range parents {
+		list, err := profileOrder(profiles, parent, chain)
+		if err != nil {
+			return order, err
+		}
+		order = append(order, list...)
+	}
+
+	return append(order, name), nil
+}
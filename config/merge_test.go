@@ -0,0 +1,185 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		dst      map[string]interface{}
+		src      map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "values replace values",
+			dst:      map[string]interface{}{"name": "app", "port": 80.0},
+			src:      map[string]interface{}{"port": 8080.0},
+			expected: map[string]interface{}{"name": "app", "port": 8080.0},
+		},
+		{
+			name:     "arrays replace arrays",
+			dst:      map[string]interface{}{"tags": []interface{}{"a"}},
+			src:      map[string]interface{}{"tags": []interface{}{"b"}},
+			expected: map[string]interface{}{"tags": []interface{}{"b"}},
+		},
+		{
+			name:     "objects are merged",
+			dst:      map[string]interface{}{"env": map[string]interface{}{"A": "1", "B": "2"}},
+			src:      map[string]interface{}{"env": map[string]interface{}{"B": "3", "C": "4"}},
+			expected: map[string]interface{}{"env": map[string]interface{}{"A": "1", "B": "3", "C": "4"}},
+		},
+		{
+			name:     "append to an array",
+			dst:      map[string]interface{}{"tags": []interface{}{"a"}},
+			src:      map[string]interface{}{"tags+": []interface{}{"b", "c"}},
+			expected: map[string]interface{}{"tags": []interface{}{"a", "b", "c"}},
+		},
+		{
+			name:     "append a single value",
+			dst:      map[string]interface{}{"tags": []interface{}{"a"}},
+			src:      map[string]interface{}{"tags+": "b"},
+			expected: map[string]interface{}{"tags": []interface{}{"a", "b"}},
+		},
+		{
+			name:     "append to a missing attribute",
+			dst:      map[string]interface{}{},
+			src:      map[string]interface{}{"tags+": []interface{}{"a"}},
+			expected: map[string]interface{}{"tags": []interface{}{"a"}},
+		},
+		{
+			name:     "append to an object merges",
+			dst:      map[string]interface{}{"env": map[string]interface{}{"A": "1"}},
+			src:      map[string]interface{}{"env+": map[string]interface{}{"B": "2"}},
+			expected: map[string]interface{}{"env": map[string]interface{}{"A": "1", "B": "2"}},
+		},
+		{
+			name:     "replace an object",
+			dst:      map[string]interface{}{"env": map[string]interface{}{"A": "1"}},
+			src:      map[string]interface{}{"env!": map[string]interface{}{"B": "2"}},
+			expected: map[string]interface{}{"env": map[string]interface{}{"B": "2"}},
+		},
+		{
+			name:     "null removes an attribute",
+			dst:      map[string]interface{}{"name": "app", "port": 80.0},
+			src:      map[string]interface{}{"port": nil},
+			expected: map[string]interface{}{"name": "app"},
+		},
+		{
+			name:     "null with a directive removes an attribute",
+			dst:      map[string]interface{}{"tags": []interface{}{"a"}},
+			src:      map[string]interface{}{"tags+": nil},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:     "nested directives",
+			dst:      map[string]interface{}{"build": map[string]interface{}{"tags": []interface{}{"a"}, "minify": true}},
+			src:      map[string]interface{}{"build": map[string]interface{}{"tags+": []interface{}{"b"}, "minify": nil}},
+			expected: map[string]interface{}{"build": map[string]interface{}{"tags": []interface{}{"a", "b"}}},
+		},
+		{
+			name:     "directives are removed from new values",
+			dst:      map[string]interface{}{},
+			src:      map[string]interface{}{"build": map[string]interface{}{"tags+": []interface{}{map[string]interface{}{"os!": "linux"}}}},
+			expected: map[string]interface{}{"build": map[string]interface{}{"tags": []interface{}{map[string]interface{}{"os": "linux"}}}},
+		},
+		{
+			name:     "single character keys are not directives",
+			dst:      map[string]interface{}{},
+			src:      map[string]interface{}{"+": "plus", "!": "bang"},
+			expected: map[string]interface{}{"+": "plus", "!": "bang"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := merge(test.dst, test.src)
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestMergeDoesNotModifyInputs(t *testing.T) {
+	dst := map[string]interface{}{"tags": []interface{}{"a"}, "env": map[string]interface{}{"A": "1"}}
+	src := map[string]interface{}{"tags+": []interface{}{"b"}, "env": map[string]interface{}{"A": nil}}
+
+	merge(dst, src)
+
+	expected := map[string]interface{}{"tags": []interface{}{"a"}, "env": map[string]interface{}{"A": "1"}}
+	if !reflect.DeepEqual(dst, expected) {
+		t.Errorf("dst was modified: %v", dst)
+	}
+	if _, exists := src["tags+"]; !exists {
+		t.Errorf("src was modified: %v", src)
+	}
+}
+
+func TestDirectiveOf(t *testing.T) {
+	tests := []struct {
+		key       string
+		name      string
+		directive string
+	}{
+		{"tags", "tags", ""},
+		{"tags+", "tags", "+"},
+		{"env!", "env", "!"},
+		{"+", "+", ""},
+		{"!", "!", ""},
+		{"", "", ""},
+	}
+
+	for _, test := range tests {
+		name, directive := directiveOf(test.key)
+		if name != test.name || directive != test.directive {
+			t.Errorf("directiveOf(%q) = (%q, %q), expected (%q, %q)", test.key, name, directive, test.name, test.directive)
+		}
+	}
+}
+
+func TestProfileOrder(t *testing.T) {
+	profiles := map[string]interface{}{
+		"base":    map[string]interface{}{},
+		"linux":   map[string]interface{}{"extends": "base"},
+		"release": map[string]interface{}{"extends": []interface{}{"base", "linux"}},
+		"deploy":  map[string]interface{}{"extends": []interface{}{"release"}},
+		"loop":    map[string]interface{}{"extends": "cycle"},
+		"cycle":   map[string]interface{}{"extends": "loop"},
+		"broken":  map[string]interface{}{"extends": "missing"},
+	}
+
+	tests := []struct {
+		name     string
+		expected []string
+		err      string
+	}{
+		{name: "base", expected: []string{"base"}},
+		{name: "linux", expected: []string{"base", "linux"}},
+		{name: "release", expected: []string{"base", "base", "linux", "release"}},
+		{name: "deploy", expected: []string{"base", "base", "linux", "release", "deploy"}},
+		{name: "loop", err: "circular profile inheritance: loop → cycle → loop"},
+		{name: "broken", err: `profile "missing" not found (extended by the "broken" profile)`},
+		{name: "missing", err: `profile "missing" not found`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order, err := profileOrder(profiles, test.name, []string{})
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(order, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, order)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Separates the attribute names of a path in the sources map. A control
+// character is used because attribute names may contain dots (ex: main.name).
+const pathSeparator = "\x1f"
+
+// Source returns the file (and profile, if applicable) a value was defined in.
+// Nested attributes are specified as a list of attribute names.
+func (cfg *Config) Source(path ...string) (string, bool) {
+	source, exists := cfg.sources[strings.Join(path, pathSeparator)]
+	return source, exists
+}
+
+// Resolved returns the effective (merged) manifest as JSON, with a comment
+// identifying where each value came from.
+func (cfg *Config) Resolved() string {
+	lines := [][2]string{}
+	cfg.annotate(&lines, []string{}, cfg.data, 0, "")
+
+	width := 0
+	for _, line := range lines {
+		if len(line[0]) > width {
+			width = len(line[0])
+		}
+	}
+
+	out := []string{}
+	for _, line := range lines {
+		if len(line[1]) > 0 {
+			out = append(out, fmt.Sprintf("%-*s  // %s", width, line[0], line[1]))
+		} else {
+			out = append(out, line[0])
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func (cfg *Config) annotate(lines *[][2]string, path []string, data map[string]interface{}, depth int, trailer string) {
+	indent := strings.Repeat("  ", depth)
+
+	prefix := indent
+	if depth > 0 {
+		name, _ := json.Marshal(path[len(path)-1])
+		prefix += string(name) + ": "
+	}
+	*lines = append(*lines, [2]string{prefix + "{", ""})
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		comma := ","
+		if i == len(keys)-1 {
+			comma = ""
+		}
+
+		subpath := append(append([]string{}, path...), key)
+		if obj, ok := data[key].(map[string]interface{}); ok && len(obj) > 0 {
+			cfg.annotate(lines, subpath, obj, depth+1, comma)
+			continue
+		}
+
+		// References are resolved when possible
+		resolved, err := cfg.resolve(data[key], "")
+		if err != nil {
+			resolved = data[key]
+		}
+
+		name, _ := json.Marshal(key)
+		value, _ := json.Marshal(resolved)
+		source, _ := cfg.Source(subpath...)
+		*lines = append(*lines, [2]string{indent + "  " + string(name) + ": " + string(value) + comma, source})
+	}
+
+	*lines = append(*lines, [2]string{indent + "}" + trailer, ""})
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Targets returns the names of the build targets defined in the "targets"
+// manifest attribute (sorted).
+func (cfg *Config) Targets() []string {
+	names := []string{}
+	if targets, ok := cfg.data["targets"].(map[string]interface{}); ok {
+		for name := range targets {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Target returns the configuration of a build target. Target attributes
+// (ex: build, name, variables, tags, output) are merged with the top-level
+// attributes of the manifest, which act as defaults for every target (except
+// name, which defaults to the name of the target).
+func (cfg *Config) Target(name string) (*Config, error) {
+	targets, _ := cfg.data["targets"].(map[string]interface{})
+	target, exists := targets[name]
+	if !exists {
+		available := cfg.Targets()
+		if len(available) == 0 {
+			return cfg, fmt.Errorf(`target "%s" not found (no targets available in %s)`, name, cfg.cfgfile)
+		}
+		return cfg, fmt.Errorf(`target "%s" not found in %s - please use one of the following: %s`, name, cfg.cfgfile, strings.Join(available, ", "))
+	}
+
+	attributes, ok := target.(map[string]interface{})
+	if !ok {
+		return cfg, fmt.Errorf(`the "%s" target must be an object`, name)
+	}
+
+	// Values defined by the target are attributed to it
+	sources := make(map[string]string, len(cfg.sources))
+	for key, value := range cfg.sources {
+		sources[key] = value
+	}
+	track(sources, []string{}, attributes, "target: "+name)
+
+	data := merge(cfg.data, attributes)
+	delete(data, "targets")
+
+	// Targets are named after the target by default, so the output of each
+	// target is unique (the top-level name is not inherited)
+	named := false
+	for key := range attributes {
+		if attr, _ := directiveOf(key); attr == "name" {
+			named = true
+		}
+	}
+	if !named {
+		data["name"] = name
+		sources["name"] = "target: " + name
+	}
+
+	return &Config{data: data, sources: sources, profiles: cfg.profiles, cfgfile: cfg.cfgfile, exists: cfg.exists}, nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestTargetName(t *testing.T) {
+	cfg := &Config{data: map[string]interface{}{
+		"name": "myapp",
+		"targets": map[string]interface{}{
+			"server": map[string]interface{}{"build": "cmd/server/main.go"},
+			"cli":    map[string]interface{}{"build": "cmd/cli/main.go", "name": "myapp"},
+			"worker": map[string]interface{}{"build": "cmd/worker/main.go", "name!": "jobs"},
+		},
+	}}
+
+	tests := map[string]string{"server": "server", "cli": "myapp", "worker": "jobs"}
+	for target, expected := range tests {
+		tc, err := cfg.Target(target)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if name, _ := tc.GetRaw("name"); name != expected {
+			t.Errorf("%s: expected name %q, got %v", target, expected, name)
+		}
+		if _, exists := tc.data["targets"]; exists {
+			t.Errorf("%s: targets should not be inherited", target)
+		}
+	}
+
+	if _, err := cfg.Target("missing"); err == nil {
+		t.Error("expected an error for a missing target")
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Update sets a top-level string attribute in the manifest file. Only the
+// value is rewritten, so comments and formatting in the file are preserved.
+func (cfg *Config) Update(key string, value string) error {
+	if cfg.cfgfile == "" {
+		return errors.New("manifest not found")
+	}
+
+	content, err := os.ReadFile(cfg.cfgfile)
+	if err != nil {
+		return err
+	}
+
+	var output []byte
+	switch strings.ToLower(filepath.Ext(cfg.cfgfile)) {
+	case ".yaml", ".yml":
+		output = updateYAML(content, key, value)
+	case ".toml":
+		output = updateTOML(content, key, value)
+	default:
+		output, err = updateJSON(content, key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	info, err := os.Stat(cfg.cfgfile)
+	if err != nil {
+		return err
+	}
+
+	if cfg.data != nil {
+		cfg.data[key] = value
+	}
+
+	return os.WriteFile(cfg.cfgfile, output, info.Mode())
+}
+
+func updateJSON(content []byte, key string, value string) ([]byte, error) {
+	// Comments are blanked out without changing byte offsets, so positions
+	// found in the stripped content apply to the original content.
+	stripped := StripJSONC(content)
+	encoded := strconv.Quote(value)
+
+	depth := 0
+	start := -1
+	for i := 0; i < len(stripped); i++ {
+		switch stripped[i] {
+		case '{', '[':
+			if depth == 0 && start < 0 {
+				start = i
+			}
+			depth++
+		case '}', ']':
+			depth--
+		case '"':
+			end := skipString(stripped, i)
+
+			// Keys are followed by a colon (values equal to the key are not keys)
+			colon := end + 1
+			for colon < len(stripped) && isSpace(stripped[colon]) {
+				colon++
+			}
+
+			if depth == 1 && string(stripped[i+1:end]) == key && colon < len(stripped) && stripped[colon] == ':' {
+				// Find the value following the colon
+				j := colon + 1
+				for j < len(stripped) && isSpace(stripped[j]) {
+					j++
+				}
+				if j >= len(stripped) || stripped[j] != '"' {
+					return content, fmt.Errorf(`"%s" is not a string value in the manifest`, key)
+				}
+				vend := skipString(stripped, j)
+
+				result := append([]byte{}, content[:j]...)
+				result = append(result, []byte(encoded)...)
+				return append(result, content[vend+1:]...), nil
+			}
+			i = end
+		}
+	}
+
+	if start < 0 {
+		return content, errors.New("manifest is not a JSON object")
+	}
+
+	// The attribute does not exist yet, so add it as the first attribute.
+	result := append([]byte{}, content[:start+1]...)
+	result = append(result, []byte(fmt.Sprintf("\n  %s: %s,", strconv.Quote(key), encoded))...)
+	return append(result, content[start+1:]...), nil
+}
+
+// skipString returns the index of the closing quote for the string starting at i.
+func skipString(content []byte, i int) int {
+	for j := i + 1; j < len(content); j++ {
+		if content[j] == '\\' {
+			j++
+		} else if content[j] == '"' {
+			return j
+		}
+	}
+
+	return len(content) - 1
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+func updateYAML(content []byte, key string, value string) []byte {
+	regex := regexp.MustCompile(`(?m)^(` + regexp.QuoteMeta(key) + `\s*:[ \t]*)("[^"\n]*"|'[^'\n]*'|[^#\n]*?)([ \t]*(#.*)?)$`)
+	if regex.Match(content) {
+		return regex.ReplaceAll(content, []byte("${1}"+escapeReplacement(strconv.Quote(value))+"${3}"))
+	}
+
+	return append([]byte(fmt.Sprintf("%s: %s\n", key, strconv.Quote(value))), content...)
+}
+
+func updateTOML(content []byte, key string, value string) []byte {
+	// Only top-level keys (those before the first table) are updated.
+	head := content
+	tail := []byte{}
+	table := regexp.MustCompile(`(?m)^\s*\[`)
+	if loc := table.FindIndex(content); loc != nil {
+		head = content[:loc[0]]
+		tail = content[loc[0]:]
+	}
+
+	regex := regexp.MustCompile(`(?m)^(` + regexp.QuoteMeta(key) + `\s*=[ \t]*)("[^"\n]*"|'[^'\n]*')`)
+	if regex.Match(head) {
+		head = regex.ReplaceAll(head, []byte("${1}"+escapeReplacement(strconv.Quote(value))))
+	} else {
+		head = append([]byte(fmt.Sprintf("%s = %s\n", key, strconv.Quote(value))), head...)
+	}
+
+	return append(append([]byte{}, head...), tail...)
+}
+
+func escapeReplacement(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "existing key",
+			input:    `{"name": "app", "version": "1.0.0"}`,
+			expected: `{"name": "app", "version": "1.0.1"}`,
+		},
+		{
+			name: "comments are retained",
+			input: `{
+  // "version": "0.0.1"
+  "version": /* current */ "1.0.0", // bumped by qgo
+}`,
+			expected: `{
+  // "version": "0.0.1"
+  "version": /* current */ "1.0.1", // bumped by qgo
+}`,
+		},
+		{
+			name:     "key in strings",
+			input:    `{"description": "\"version\": \"9.9.9\"", "version": "1.0.0"}`,
+			expected: `{"description": "\"version\": \"9.9.9\"", "version": "1.0.1"}`,
+		},
+		{
+			name:     "values equal to the key",
+			input:    `{"description": "version", "tags": ["version"], "version" : "1.0.0"}`,
+			expected: `{"description": "version", "tags": ["version"], "version" : "1.0.1"}`,
+		},
+		{
+			name:     "nested keys are ignored",
+			input:    `{"build": {"version": "2.0.0"}, "version": "1.0.0"}`,
+			expected: `{"build": {"version": "2.0.0"}, "version": "1.0.1"}`,
+		},
+		{
+			name:     "nested keys only",
+			input:    `{"build": {"version": "2.0.0"}}`,
+			expected: "{\n  \"version\": \"1.0.1\"," + `"build": {"version": "2.0.0"}}`,
+		},
+		{
+			name:     "missing key",
+			input:    "{\n  \"name\": \"app\",\n}",
+			expected: "{\n  \"version\": \"1.0.1\",\n  \"name\": \"app\",\n}",
+		},
+		{
+			name:  "not a string",
+			input: `{"version": 1}`,
+			err:   true,
+		},
+		{
+			name:  "not an object",
+			input: `// empty`,
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := updateJSON([]byte(test.input), "version", "1.0.1")
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(output) != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestUpdateYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"unquoted", "name: app\nversion: 1.0.0\n", "name: app\nversion: \"1.0.1\"\n"},
+		{"quoted", "version: '1.0.0'\n", "version: \"1.0.1\"\n"},
+		{"comment", "version: \"1.0.0\"  # current\n", "version: \"1.0.1\"  # current\n"},
+		{"nested keys are ignored", "build:\n  version: 2.0.0\nversion: 1.0.0\n", "build:\n  version: 2.0.0\nversion: \"1.0.1\"\n"},
+		{"missing key", "# manifest\nname: app\n", "version: \"1.0.1\"\n# manifest\nname: app\n"},
+		{"commented key", "# version: 0.0.1\nname: app\n", "version: \"1.0.1\"\n# version: 0.0.1\nname: app\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := string(updateYAML([]byte(test.input), "version", "1.0.1"))
+			if output != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestUpdateTOML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"double quoted", "name = \"app\"\nversion = \"1.0.0\"\n", "name = \"app\"\nversion = \"1.0.1\"\n"},
+		{"single quoted", "version = '1.0.0' # current\n", "version = \"1.0.1\" # current\n"},
+		{"table keys are ignored", "version = \"1.0.0\"\n[build]\nversion = \"2.0.0\"\n", "version = \"1.0.1\"\n[build]\nversion = \"2.0.0\"\n"},
+		{"missing key", "name = \"app\"\n[build]\nversion = \"2.0.0\"\n", "version = \"1.0.1\"\nname = \"app\"\n[build]\nversion = \"2.0.0\"\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := string(updateTOML([]byte(test.input), "version", "1.0.1"))
+			if output != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestUpdateEscapesReplacements(t *testing.T) {
+	if output := string(updateYAML([]byte("version: 1.0.0\n"), "version", "$1")); output != "version: \"$1\"\n" {
+		t.Errorf("unexpected YAML output: %s", output)
+	}
+	if output := string(updateTOML([]byte("version = \"1.0.0\"\n"), "version", "$1")); output != "version = \"$1\"\n" {
+		t.Errorf("unexpected TOML output: %s", output)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "manifest.jsonc")
+	if err := os.WriteFile(file, []byte("{\n  // app\n  \"version\": \"1.0.0\",\n}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{cfgfile: file, data: map[string]interface{}{"version": "1.0.0"}}
+	if err := cfg.Update("version", "1.0.1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, _ := os.ReadFile(file)
+	if expected := "{\n  // app\n  \"version\": \"1.0.1\",\n}"; string(content) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, content)
+	}
+	if cfg.data["version"] != "1.0.1" {
+		t.Errorf("expected the loaded data to be updated, got %v", cfg.data["version"])
+	}
+
+	if err := (&Config{}).Update("version", "1.0.1"); err == nil {
+		t.Error("expected an error without a manifest")
+	}
+}
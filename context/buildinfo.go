@@ -0,0 +1,137 @@
+package context
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/quikdev/go/util"
+)
+
+//go:embed assets/buildinfo.go.tpl
+var buildinfoTemplate []byte
+
+// BuildInfo describes the generated build information package.
+type BuildInfo struct {
+	Dir        string // Absolute path of the package directory
+	ImportPath string // ex: github.com/org/app/internal/buildinfo
+	Package    string // ex: buildinfo
+}
+
+// BuildInfo returns the build information package configuration, which is
+// enabled with the "buildinfo" manifest attribute:
+//
+//	"buildinfo": true
+//	"buildinfo": {"package": "internal/buildinfo"}
+//
+// The generated package is meant to be committed (it does not change between
+// builds), so the module builds without qgo (ex: in a fresh clone).
+func (ctx *Context) BuildInfo() (*BuildInfo, bool, error) {
+	value, exists := ctx.config.Get("buildinfo")
+	if !exists {
+		return nil, false, nil
+	}
+
+	dir := "internal/buildinfo"
+	switch v := value.(type) {
+	case bool:
+		if !v {
+			return nil, false, nil
+		}
+	case map[string]interface{}:
+		if pkg, ok := v["package"].(string); ok && len(strings.TrimSpace(pkg)) > 0 {
+			dir = strings.Trim(filepath.ToSlash(pkg), "/")
+		}
+	default:
+		return nil, false, fmt.Errorf(`invalid "buildinfo" value (expected true or an object)`)
+	}
+
+	root, found := util.FindModuleRoot(ctx.CWD)
+	if !found {
+		return nil, false, fmt.Errorf("cannot generate the buildinfo package (go.mod not found)")
+	}
+
+	module, err := modulePath(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &BuildInfo{
+		Dir:        filepath.Join(root, filepath.FromSlash(dir)),
+		ImportPath: module + "/" + dir,
+		Package:    strings.ReplaceAll(path.Base(dir), "-", "_"),
+	}, true, nil
+}
+
+// modulePath returns the module path declared in a go.mod file.
+func modulePath(file string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(line[len("module "):]), `"`), nil
+		}
+	}
+
+	return "", fmt.Errorf("module path not found in %s", file)
+}
+
+// linkBuildInfo sets the build information package values from the manifest.
+func (ctx *Context) linkBuildInfo() {
+	info, enabled, err := ctx.BuildInfo()
+	if err != nil {
+		util.Stderr(err, true)
+	}
+
+	if !enabled {
+		return
+	}
+
+	for _, attr := range []string{"name", "version", "description"} {
+		if value, exists := ctx.config.Get(attr); exists {
+			if str, ok := value.(string); ok && len(str) > 0 {
+				ctx.AddLinkedVariable(info.ImportPath+"."+strings.ToUpper(attr[:1])+attr[1:], str)
+			}
+		}
+	}
+
+	// When unavailable, the VCS information embedded by Go is used instead
+	if commit, _, err := ctx.Resolve("git.commit"); err == nil && len(commit) > 0 {
+		ctx.AddLinkedVariable(info.ImportPath+".Commit", commit)
+	}
+
+	ctx.AddLinkedVariable(info.ImportPath+".BuildTime", buildTime().Format(time.RFC3339))
+}
+
+// GenerateBuildInfo writes the build information package (when enabled).
+// Files are only written when their content changes, so generating the
+// package does not invalidate the build cache.
+func (ctx *Context) GenerateBuildInfo() error {
+	info, enabled, err := ctx.BuildInfo()
+	if err != nil || !enabled {
+		return err
+	}
+
+	if err := os.MkdirAll(info.Dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	content := util.ApplyTemplate(buildinfoTemplate, info)
+	file := filepath.Join(info.Dir, "buildinfo.go")
+	if existing, err := os.ReadFile(file); err == nil && string(existing) == content {
+		return nil
+	}
+
+	return os.WriteFile(file, []byte(content), 0644)
+}
@@ -7,21 +7,27 @@ import (
 	"regexp"
 	"runtime"
 	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	fs "github.com/coreybutler/go-fsutil"
 	"github.com/quikdev/go/command"
 	"github.com/quikdev/go/config"
+	"github.com/quikdev/go/hook"
 	"github.com/quikdev/go/util"
 )
 
 type Context struct {
 	config              *config.Config
+	Target              string            `json:"target,omitempty"`
 	Image               string            `json:"image,omitempty"`
 	Env                 map[string]string `json:"environment_variables"`
 	Variables           []string          `json:"ldflag_variables"`
 	WASM                bool              `json:"wasm"`
+	WASMTarget          string            `json:"wasm_target,omitempty"`  // js or wasip1
+	WASMRuntime         string            `json:"wasm_runtime,omitempty"` // browser (default) or node (js/wasm only)
+	WASMOptimize        *WASMOptimization `json:"wasm_optimize,omitempty"`
 	OS                  []string          `json:"operating_systems"`
 	BuildFlags          []string          `json:"build_flags"`
 	StripSymbols        bool              `json:"strip_symbols,omitempty"`
@@ -45,11 +51,13 @@ type Context struct {
 	Tidy                bool              `json:"auto_tidy"`
 	Tiny                bool              `json:"use_tinygo"`
 	UPX                 bool              `json:"use_upx"`
+	Reproducible        bool              `json:"reproducible"`
+	SBOMFormat          string            `json:"sbom_format,omitempty"` // Overrides the "sbom" manifest attribute
 	BuildFast           bool              `json:"build_fast"`
-	PreRun              []string          `json:"before_run"`
-	PostRun             []string          `json:"after_run"`
-	PreBuild            []string          `json:"before_build"`
-	PostBuild           []string          `json:"after_build"`
+	PreRun              []*Hook           `json:"before_run"`
+	PostRun             []*Hook           `json:"after_run"`
+	PreBuild            []*Hook           `json:"before_build"`
+	PostBuild           []*Hook           `json:"after_build"`
 	Port                int
 	IgnoreCache         bool
 	Cached              bool
@@ -57,13 +65,20 @@ type Context struct {
 }
 
 func New(profiles ...string) *Context {
+	// The config is loaded first because locating the manifest
+	// may change the working directory.
+	return FromConfig(config.New(profiles...))
+}
+
+// FromConfig creates a context from an existing configuration.
+func FromConfig(cfg *config.Config) *Context {
 	wd, err := os.Getwd()
 	if err != nil {
 		wd = "./"
 	}
 
-	return &Context{
-		config:              config.New(profiles...),
+	ctx := &Context{
+		config:              cfg,
 		Env:                 make(map[string]string),
 		Variables:           []string{},
 		BuildFlags:          []string{},
@@ -88,12 +103,38 @@ func New(profiles ...string) *Context {
 		IgnoreCache:         false,
 		Cached:              false,
 	}
+
+	// Manifest values may reference the same sources as variables (${...})
+	cfg.SetResolver(ctx.Resolve)
+
+	// The build time of reproducible builds is the source date epoch, which
+	// must be known before any time-based value is resolved
+	if reproducible, _ := cfg.GetRaw("reproducible"); reproducible == true {
+		ctx.Reproducible = true
+		util.BailOnError(useSourceDateEpoch())
+	}
+
+	return ctx
 }
 
 func (ctx *Context) GetConfig() *config.Config {
 	return ctx.config
 }
 
+// ForTarget creates a context for a build target defined in the "targets"
+// manifest attribute (see config.Target).
+func (ctx *Context) ForTarget(name string) (*Context, error) {
+	cfg, err := ctx.config.Target(name)
+	if err != nil {
+		return ctx, err
+	}
+
+	target := FromConfig(cfg)
+	target.Target = name
+
+	return target, nil
+}
+
 func (ctx *Context) InputFile() string {
 	if build, exists := ctx.config.Get("build"); exists {
 		return build.(string)
@@ -175,75 +216,67 @@ func (ctx *Context) Configure() {
 		ctx.OutputFileName = regex.ReplaceAllString(strings.ReplaceAll(out.(string), " ", "_"), "")
 	}
 
-	// Read linked variables from config
-	if list, exists := ctx.config.Get("variables"); exists {
-		for key, value := range list.(map[string]interface{}) {
-			if strings.HasPrefix(strings.ToLower(value.(string)), "package.") || strings.HasPrefix(strings.ToLower(value.(string)), "manifest.") {
-				subkey := strings.Split(value.(string), ".")[1:]
-				if val, exists := ctx.config.Get(subkey[0]); exists {
-					ctx.AddLinkedVariable(key, val.(string))
-				} else {
-					ctx.AddLinkedVariable(key, value.(string))
+	// Read linked variables from config (as written, so each value is only
+	// interpolated once and escaped $${...} expressions stay literal)
+	if list, exists := ctx.config.GetRaw("variables"); exists {
+		variables := list.(map[string]interface{})
+		keys := make([]string, 0, len(variables))
+		for key := range variables {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := variables[key].(string)
+			val := value
+			var err error
+
+			// Values such as manifest.version and env.USER are resolved, while
+			// other sources are written as ${...} (so literal values such as
+			// "file.txt" are not mistaken for sources)
+			switch source, _, _ := strings.Cut(value, "."); strings.ToLower(source) {
+			case "manifest", "package":
+				val, _, err = ctx.Resolve(value)
+				if err == nil {
+					// Manifest attributes may contain references themselves
+					val, err = ctx.config.Interpolate(val)
 				}
-			} else if strings.HasPrefix(strings.ToLower(value.(string)), "env.") {
-				subkey := strings.Split(value.(string), ".")[1:]
-				val := os.Getenv(subkey[0])
-				ctx.AddLinkedVariable(key, val)
-			} else {
-				ctx.AddLinkedVariable(key, value.(string))
+			case "env":
+				val, _, err = ctx.Resolve(value)
+			default:
+				val, err = ctx.config.Interpolate(value)
+			}
+			if err != nil {
+				util.Stderr(fmt.Sprintf("%s: %v\n", key, err))
 			}
+			ctx.AddLinkedVariable(key, val)
 		}
 	}
 
+	// Link the values of the generated build information package
+	ctx.linkBuildInfo()
+
 	if kill, exists := ctx.config.Get("prekill"); exists {
 		ctx.Prekill = kill.(bool)
 	}
 
-	if prerun, exists := ctx.config.Get("prerun"); exists {
-		val, ok := prerun.([]interface{})
-		if ok {
-			ctx.PreRun = []string{}
-			for _, value := range val {
-				ctx.PreRun = append(ctx.PreRun, value.(string))
-			}
-		} else if val2, ok2 := prerun.(string); ok2 {
-			ctx.PreRun = []string{val2}
-		}
-	}
-
-	if postrun, exists := ctx.config.Get("postrun"); exists {
-		val, ok := postrun.([]interface{})
-		if ok {
-			ctx.PostRun = []string{}
-			for _, value := range val {
-				ctx.PostRun = append(ctx.PostRun, value.(string))
+	for _, stage := range []string{hook.PreRun, hook.PostRun, hook.PreBuild, hook.PostBuild} {
+		if value, exists := ctx.config.Get(stage); exists {
+			hooks, err := parseHooks(stage, value)
+			if err != nil {
+				util.Stderr(err, true)
 			}
-		} else if val2, ok2 := postrun.(string); ok2 {
-			ctx.PostRun = []string{val2}
-		}
-	}
 
-	if prebuild, exists := ctx.config.Get("prebuild"); exists {
-		val, ok := prebuild.([]interface{})
-		if ok {
-			ctx.PreBuild = []string{}
-			for _, value := range val {
-				ctx.PreBuild = append(ctx.PreBuild, value.(string))
+			switch stage {
+			case hook.PreRun:
+				ctx.PreRun = hooks
+			case hook.PostRun:
+				ctx.PostRun = hooks
+			case hook.PreBuild:
+				ctx.PreBuild = hooks
+			case hook.PostBuild:
+				ctx.PostBuild = hooks
 			}
-		} else if val2, ok2 := prebuild.(string); ok2 {
-			ctx.PreBuild = []string{val2}
-		}
-	}
-
-	if postbuild, exists := ctx.config.Get("postbuild"); exists {
-		val, ok := postbuild.([]interface{})
-		if ok {
-			ctx.PostBuild = []string{}
-			for _, value := range val {
-				ctx.PostBuild = append(ctx.PostBuild, value.(string))
-			}
-		} else if val2, ok2 := postbuild.(string); ok2 {
-			ctx.PostBuild = []string{val2}
 		}
 	}
 
@@ -378,7 +411,30 @@ func (ctx *Context) Configure() {
 
 	// Configure WASM builds
 	if wasm, exists := ctx.config.Get("wasm"); exists {
-		ctx.WASM = wasm.(bool)
+		switch value := wasm.(type) {
+		case bool:
+			ctx.WASM = value
+		case string:
+			if value != "js" && value != "wasip1" {
+				util.Stderr(fmt.Sprintf(`invalid "wasm" value "%s" (expected true, "js", or "wasip1")`, value), true)
+			}
+			ctx.WASM = true
+			ctx.WASMTarget = value
+		case map[string]interface{}:
+			ctx.WASM = true
+			ctx.WASMTarget = "js"
+			if target, ok := value["target"].(string); ok {
+				if target != "js" && target != "wasip1" {
+					util.Stderr(fmt.Sprintf(`invalid wasm target "%s" (expected "js" or "wasip1")`, target), true)
+				}
+				ctx.WASMTarget = target
+			}
+			if optimize, exists := value["optimize"]; exists {
+				optimization, err := parseWASMOptimization(optimize)
+				util.BailOnError(err)
+				ctx.WASMOptimize = optimization
+			}
+		}
 	}
 
 	// Configure build optimizations
@@ -449,11 +505,23 @@ func (ctx *Context) isOutdated(bin string) bool {
 		return false
 	}
 
-	return lastChange.After(binInfo.ModTime())
+	// The output of a reproducible build has a fixed modification time, so
+	// the time it was built is recorded separately
+	modified := binInfo.ModTime()
+	if ctx.Reproducible {
+		stamp, err := os.Stat(stampFile(bin))
+		if err != nil {
+			return true
+		}
+		modified = stamp.ModTime()
+	}
+
+	return lastChange.After(modified)
 }
 
 func (ctx *Context) BuildCommand(colorized ...bool) *command.Command {
 	cmd := command.New()
+	cmd.SetEnv(ctx.config.GetEnvVarList()...)
 
 	// Identify output file
 	out := strings.Replace(ctx.Output(), ctx.CWD, ".", 1)
@@ -500,12 +568,12 @@ func (ctx *Context) BuildCommand(colorized ...bool) *command.Command {
 	}
 
 	if ctx.WASM {
-		os.Setenv("GOOS", "js")
+		os.Setenv("GOOS", ctx.wasmOS())
 		os.Setenv("GOARCH", "wasm")
 	}
 
 	// Linked Flags
-	ctx.AddLinkedVariable("main.buildTime", time.Now().UTC().Format(time.RFC3339))
+	ctx.AddLinkedVariable("main.buildTime", buildTime().Format(time.RFC3339))
 
 	if ctx.StripDebugging {
 		ctx.AddLinkedFlag("-w")
@@ -550,6 +618,12 @@ func (ctx *Context) BuildCommand(colorized ...bool) *command.Command {
 		cmd.Add("-v")
 	}
 
+	// Reproducible builds remove file system paths and version control
+	// information (TinyGo does not support these flags)
+	if ctx.Reproducible && !ctx.Tiny {
+		cmd.Add(ctx.reproducibleFlags()...)
+	}
+
 	if len(ldflags) > 0 {
 		cmd.Add("-ldflags", "\""+strings.Join(ldflags, " ")+"\"")
 	}
@@ -577,7 +651,11 @@ func (ctx *Context) BuildCommand(colorized ...bool) *command.Command {
 	cmd.Add("-o", out)
 
 	if ctx.Tiny {
-		cmd.Add("-target=wasm")
+		if ctx.WASI() {
+			cmd.Add("-target=wasip1")
+		} else {
+			cmd.Add("-target=wasm")
+		}
 	}
 
 	cmd.Add(strings.TrimSpace(strings.ReplaceAll(ctx.InputFile(), " ", "\\ ")))
@@ -588,37 +666,45 @@ func (ctx *Context) BuildCommand(colorized ...bool) *command.Command {
 func (ctx *Context) RunCommand(colorized ...bool) *command.Command {
 	cmd := ctx.BuildCommand(colorized...)
 
-	if !ctx.WASM {
+	if !ctx.Browser() {
 		out := strings.Replace(ctx.Output(), ctx.CWD, ".", 1)
 		out = strings.Replace(out, ".go", "", 1)
 		// out = strings.ReplaceAll(out, "\\", "/")
 		if len(strings.TrimSpace(cmd.String())) > 0 {
 			cmd.Add("&&")
 		}
+
+		// WASI modules are run by a WASI runtime, js/wasm binaries by Node.js
+		if ctx.WASM {
+			launcher, err := ctx.WASIRuntime()
+			if !ctx.WASI() {
+				launcher, err = WASMNode()
+			}
+			if err != nil {
+				util.Stderr(err, true)
+			}
+			cmd.Add(launcher...)
+		}
 		cmd.Add(out)
 
-		// append arguments supplied by the user
+		// append arguments supplied by the user (after the command, without
+		// the root --manifest flag)
 		// ignore the build file, or the `--` separator if it exists.
-		args := os.Args[2:]
-		if len(args) > 0 {
-			index := util.IndexOf[string](args, "--")
-			if index >= 0 {
-				args = args[index+1:]
-			} else if len(args) > 0 {
-				if filepath.Ext(args[0]) == ".go" {
-					args = args[1:]
-				}
-			}
+		_, args := util.Subcommand(os.Args[1:])
+		positional := true
+		if index := util.IndexOf[string](args, "--"); index >= 0 {
+			args = args[index+1:]
+			positional = false
 		}
 
 		// Ignore the no-cache flag
-		ignoreList := []string{"bundle", "os", "wasm", "output", "tips", "minify", "shrink", "dry-run", "nowork", "update", "port", "no-cache", "profile"}
+		ignoreList := []string{"bundle", "os", "wasm", "output", "tips", "minify", "shrink", "dry-run", "nowork", "update", "port", "no-cache", "profile", "runtime"}
 		for _, ignored := range ignoreList {
 			for {
 				i := util.IndexOf[string](args, "--"+ignored)
 				if i >= 0 {
 					end := i + 1
-					if ignored == "profile" {
+					if ignored == "profile" || ignored == "runtime" {
 						end += 1
 					}
 					args = slices.Delete(args, i, end)
@@ -629,6 +715,13 @@ func (ctx *Context) RunCommand(colorized ...bool) *command.Command {
 			}
 		}
 
+		// The source file or target name is the first positional argument
+		// (arguments after it, including one matching the target name, are
+		// passed to the app)
+		if positional && len(args) > 0 && (filepath.Ext(args[0]) == ".go" || (len(ctx.Target) > 0 && args[0] == ctx.Target)) {
+			args = args[1:]
+		}
+
 		if len(args) > 0 {
 			cmd.Add(args...)
 		}
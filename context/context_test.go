@@ -0,0 +1,95 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestContext creates a context from a manifest (in a temporary directory,
+// which becomes the working directory until the test ends).
+func newTestContext(t *testing.T, manifest string, files ...string) *Context {
+	t.Helper()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "manifest.json")
+	if err := os.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(files); i += 2 {
+		if err := os.WriteFile(filepath.Join(dir, files[i]), []byte(files[i+1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("QGO_MANIFEST", file)
+
+	return New()
+}
+
+func TestConfigureVariables(t *testing.T) {
+	t.Setenv("QGO_TEST_USER", "tester")
+
+	ctx := newTestContext(t, `{
+  "name": "app",
+  "version": "1.2.3",
+  "variables": {
+    "main.version": "manifest.version",
+    "main.user": "env.QGO_TEST_USER",
+    "main.file": "file.txt",
+    "main.exe": "cmd.exe",
+    "main.release": "${manifest.version}-${env.QGO_TEST_USER}",
+    "main.literal": "some value"
+  }
+}`)
+	ctx.Configure()
+
+	expected := []string{
+		"-X 'main.exe=cmd.exe'",
+		"-X 'main.file=file.txt'",
+		"-X 'main.literal=some value'",
+		"-X 'main.release=1.2.3-tester'",
+		"-X 'main.user=tester'",
+		"-X 'main.version=1.2.3'",
+	}
+	for _, variable := range expected {
+		found := false
+		for _, v := range ctx.Variables {
+			if v == variable {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %s in %v", variable, ctx.Variables)
+		}
+	}
+}
+
+// Escaped expressions are literal values (the value is only interpolated once).
+func TestConfigureEscapedVariables(t *testing.T) {
+	t.Setenv("QGO_GIT_COMMIT", "0123456789abcdef")
+
+	ctx := newTestContext(t, `{
+  "version": "$${git.commit}",
+  "variables": {
+    "main.escaped": "$${git.commit}",
+    "main.mixed": "${git.commit} $${git.commit}",
+    "main.version": "manifest.version"
+  }
+}`)
+	ctx.Configure()
+
+	expected := map[string]bool{
+		"-X 'main.escaped=${git.commit}'":                true,
+		"-X 'main.mixed=0123456789abcdef ${git.commit}'": true,
+		"-X 'main.version=${git.commit}'":                true,
+	}
+	for _, v := range ctx.Variables {
+		delete(expected, v)
+	}
+	if len(expected) > 0 {
+		t.Errorf("missing %v in %v", expected, ctx.Variables)
+	}
+}
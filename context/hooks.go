@@ -0,0 +1,157 @@
+package context
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/quikdev/go/hook"
+	"github.com/quikdev/go/util"
+)
+
+// Hook is a command run before or after a build/run. It receives the build
+// event as JSON on stdin and through QGO_* environment variables.
+type Hook struct {
+	Command string
+	OnError string // fail (default), warn, or ignore
+}
+
+// parseHooks reads a hook attribute, which is a command, an object, or a list
+// of either:
+//
+//	"postbuild": "./scripts/sign.sh"
+//	"postbuild": {"command": "go run ./hooks/notify", "on_error": "warn"}
+//	"postbuild": ["./scripts/sign.sh", {"command": "...", "on_error": "ignore"}]
+func parseHooks(name string, value interface{}) ([]*Hook, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+
+	hooks := []*Hook{}
+	for _, item := range items {
+		h := &Hook{OnError: "fail"}
+
+		switch v := item.(type) {
+		case string:
+			h.Command = v
+		case map[string]interface{}:
+			h.Command, _ = v["command"].(string)
+			if policy, ok := v["on_error"].(string); ok {
+				h.OnError = policy
+			}
+		default:
+			return hooks, fmt.Errorf(`invalid "%s" hook (expected a command or an object)`, name)
+		}
+
+		if len(h.Command) == 0 {
+			return hooks, fmt.Errorf(`the "%s" hook requires a command`, name)
+		}
+
+		switch h.OnError {
+		case "fail", "warn", "ignore":
+		default:
+			return hooks, fmt.Errorf(`invalid on_error policy "%s" for the "%s" hook (expected fail, warn, or ignore)`, h.OnError, name)
+		}
+
+		hooks = append(hooks, h)
+	}
+
+	return hooks, nil
+}
+
+// Hooks returns the hooks of a stage (prebuild, postbuild, prerun, or postrun).
+func (ctx *Context) Hooks(stage string) []*Hook {
+	switch stage {
+	case hook.PreBuild:
+		return ctx.PreBuild
+	case hook.PostBuild:
+		return ctx.PostBuild
+	case hook.PreRun:
+		return ctx.PreRun
+	case hook.PostRun:
+		return ctx.PostRun
+	}
+
+	return []*Hook{}
+}
+
+// AddHook adds a hook (ex: from a command line flag) to a stage.
+func (ctx *Context) AddHook(stage string, command string) {
+	h := &Hook{Command: command, OnError: "fail"}
+
+	switch stage {
+	case hook.PreBuild:
+		ctx.PreBuild = append(ctx.PreBuild, h)
+	case hook.PostBuild:
+		ctx.PostBuild = append(ctx.PostBuild, h)
+	case hook.PreRun:
+		ctx.PreRun = append(ctx.PreRun, h)
+	case hook.PostRun:
+		ctx.PostRun = append(ctx.PostRun, h)
+	}
+}
+
+// Event creates the event provided to the hooks of a stage. The duration and
+// success of post hooks are set by the caller.
+func (ctx *Context) Event(stage string) *hook.Event {
+	event := &hook.Event{
+		Stage:   stage,
+		Target:  ctx.Target,
+		Name:    ctx.OutputFileName,
+		Output:  ctx.Output(),
+		OS:      ctx.targetOS(),
+		Arch:    ctx.targetArch(),
+		Success: true,
+	}
+
+	if version, exists := ctx.config.Get("version"); exists {
+		event.Version = fmt.Sprintf("%v", version)
+	}
+
+	return event
+}
+
+// RunHooks runs the hooks of the event stage. When a hook fails, qgo stops
+// (on_error: fail), displays a warning (on_error: warn), or continues silently
+// (on_error: ignore).
+func (ctx *Context) RunHooks(event *hook.Event, hide ...bool) {
+	quiet := len(hide) > 0 && hide[0]
+
+	payload, _ := json.Marshal(event)
+	for _, h := range ctx.Hooks(event.Stage) {
+		if !quiet {
+			util.Highlight(h.Command)
+		}
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/c", h.Command)
+		} else {
+			cmd = exec.Command("sh", "-c", h.Command)
+		}
+		cmd.Dir = ctx.CWD
+		cmd.Env = append(os.Environ(), event.Env()...)
+		cmd.Stdin = bytes.NewReader(payload)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		start := time.Now()
+		err := cmd.Run()
+		if err != nil {
+			switch h.OnError {
+			case "warn":
+				util.Stderr(fmt.Sprintf("%s hook failed after %s (%v): %s\n", event.Stage, time.Since(start).Round(time.Millisecond), err, h.Command))
+			case "ignore":
+			default:
+				util.Stderr(fmt.Sprintf("%s hook failed (%v): %s\n", event.Stage, err, h.Command), true)
+			}
+		}
+
+		fmt.Println("")
+	}
+}
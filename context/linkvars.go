@@ -0,0 +1,417 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/quikdev/go/util"
+)
+
+// LinkVariable is a package-level variable that may be set with -X.
+type LinkVariable struct {
+	Package  string // The package as referenced by -X (ex: main or github.com/org/app/version)
+	Name     string
+	Type     string
+	Value    string // The initial value (when it is a string literal)
+	File     string
+	Line     int
+	Linkable bool   // -X only sets package-level string variables initialized to a constant (or not initialized)
+	Reason   string // Explains why the variable is not linkable
+}
+
+func (v *LinkVariable) Target() string {
+	return v.Package + "." + v.Name
+}
+
+func (v *LinkVariable) Location() string {
+	return fmt.Sprintf("%s:%d", v.File, v.Line)
+}
+
+// splitTarget splits an -X target (ex: github.com/org/app/version.Number)
+// into the package and variable name.
+func splitTarget(target string) (string, string, bool) {
+	i := strings.LastIndex(target, ".")
+	if i <= 0 || i <= strings.LastIndex(target, "/") || i == len(target)-1 {
+		return "", "", false
+	}
+
+	return target[:i], target[i+1:], true
+}
+
+// goFiles lists the Go files of a package (respecting build constraints
+// and tags) using "go list". The "main" package is the application input.
+func (ctx *Context) goFiles(pkg string) ([]string, error) {
+	target := pkg
+	if pkg == "main" {
+		target = ctx.InputFile()
+		if len(strings.TrimSpace(target)) == 0 {
+			target = "."
+		}
+	}
+
+	args := []string{"list", "-e", "-json"}
+	if len(ctx.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(ctx.Tags, ","))
+	}
+	args = append(args, target)
+
+	// Packages are only inspected locally (missing modules are not downloaded)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = ctx.CWD
+	cmd.Env = append(os.Environ(), "GOPROXY=off")
+	out, err := cmd.Output()
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			return []string{}, fmt.Errorf("cannot list package %s: %s", pkg, strings.TrimSpace(string(exit.Stderr)))
+		}
+		return []string{}, err
+	}
+
+	var info struct {
+		Dir     string
+		GoFiles []string
+		Error   *struct{ Err string }
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return []string{}, err
+	}
+
+	if info.Error != nil {
+		return []string{}, fmt.Errorf("cannot find package %s", pkg)
+	}
+
+	files := make([]string, 0, len(info.GoFiles))
+	for _, file := range info.GoFiles {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(info.Dir, file)
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// LinkVariables inspects the package-level string variables of a package.
+func (ctx *Context) LinkVariables(pkg string) ([]*LinkVariable, error) {
+	files, err := ctx.goFiles(pkg)
+	if err != nil {
+		return []*LinkVariable{}, err
+	}
+
+	fset := token.NewFileSet()
+	parsed := []*ast.File{}
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return []*LinkVariable{}, err
+		}
+		parsed = append(parsed, f)
+	}
+
+	// Resolve the types of untyped variables (ex: var version = prefix + "1.0").
+	// Imported packages are not loaded, so values referencing them have an
+	// unknown type.
+	info := &types.Info{Defs: map[*ast.Ident]types.Object{}}
+	conf := types.Config{Importer: unavailableImporter{}, Error: func(error) {}}
+	conf.Check(pkg, fset, parsed, info)
+
+	// Identify package-level constants, which may initialize a linkable variable
+	constants := map[string]bool{}
+	for _, f := range parsed {
+		for _, decl := range f.Decls {
+			if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.CONST {
+				for _, spec := range gen.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						constants[name.Name] = true
+					}
+				}
+			}
+		}
+	}
+
+	result := []*LinkVariable{}
+	for _, f := range parsed {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+
+			for _, s := range gen.Specs {
+				spec := s.(*ast.ValueSpec)
+				for i, name := range spec.Names {
+					if name.Name == "_" {
+						continue
+					}
+
+					pos := fset.Position(name.Pos())
+					file := pos.Filename
+					if rel, err := filepath.Rel(ctx.CWD, file); err == nil {
+						file = rel
+					}
+
+					v := &LinkVariable{
+						Package:  pkg,
+						Name:     name.Name,
+						File:     filepath.ToSlash(file),
+						Line:     pos.Line,
+						Linkable: true,
+					}
+
+					var value ast.Expr
+					if len(spec.Values) == len(spec.Names) {
+						value = spec.Values[i]
+					} else if len(spec.Values) > 0 {
+						// ex: a, b = fn()
+						value = spec.Values[0]
+					}
+
+					if spec.Type != nil {
+						v.Type = typeName(spec.Type)
+					} else if value != nil {
+						v.Type = resolvedType(info.Defs[name])
+					}
+
+					if v.Type != "string" && v.Type != "" {
+						v.Linkable = false
+						v.Reason = fmt.Sprintf("type %s, not string", v.Type)
+					} else if value != nil && (!isConstant(value, constants) || len(spec.Values) != len(spec.Names)) {
+						v.Linkable = false
+						v.Reason = "initialized to a non-constant value"
+					} else if v.Type == "" {
+						v.Linkable = false
+						v.Reason = "unknown type"
+					} else if lit, ok := value.(*ast.BasicLit); ok {
+						if str, err := strconv.Unquote(lit.Value); err == nil {
+							v.Value = str
+						}
+					}
+
+					result = append(result, v)
+				}
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// typeName returns a readable name for a type expression.
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return typeName(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + typeName(t.X)
+	case *ast.ArrayType:
+		return "[]" + typeName(t.Elt)
+	case *ast.MapType:
+		return "map[" + typeName(t.Key) + "]" + typeName(t.Value)
+	}
+
+	return "?"
+}
+
+// resolvedType returns the type of a variable resolved by go/types (empty
+// when the type is unknown).
+func resolvedType(obj types.Object) string {
+	if obj == nil || obj.Type() == nil || obj.Type() == types.Typ[types.Invalid] {
+		return ""
+	}
+
+	return types.TypeString(obj.Type(), func(p *types.Package) string { return p.Name() })
+}
+
+// unavailableImporter does not load imported packages (only the types of the
+// inspected package are resolved).
+type unavailableImporter struct{}
+
+func (unavailableImporter) Import(path string) (*types.Package, error) {
+	return nil, fmt.Errorf("%s is not loaded", path)
+}
+
+// isConstant determines whether an expression is a constant (string) expression.
+func isConstant(expr ast.Expr, constants map[string]bool) bool {
+	switch value := expr.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.Ident:
+		return constants[value.Name]
+	case *ast.BinaryExpr:
+		return isConstant(value.X, constants) && isConstant(value.Y, constants)
+	case *ast.ParenExpr:
+		return isConstant(value.X, constants)
+	case *ast.CallExpr:
+		if ident, ok := value.Fun.(*ast.Ident); ok && ident.Name == "string" && len(value.Args) == 1 {
+			return isConstant(value.Args[0], constants)
+		}
+	}
+
+	return false
+}
+
+// CheckVariables validates the targets of the manifest variables, returning
+// a description of each problem (ex: a variable that does not exist).
+func (ctx *Context) CheckVariables() []string {
+	problems := []string{}
+
+	list, exists := ctx.config.Get("variables")
+	if !exists {
+		return problems
+	}
+
+	variables, ok := list.(map[string]interface{})
+	if !ok {
+		return problems
+	}
+
+	targets := make([]string, 0, len(variables))
+	for target := range variables {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	packages := map[string][]*LinkVariable{}
+	failed := map[string]bool{}
+
+	for _, target := range targets {
+		pkg, name, ok := splitTarget(target)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is not a valid variable (expected <package>.<name>)", target))
+			continue
+		}
+
+		if failed[pkg] {
+			continue
+		}
+
+		if _, exists := packages[pkg]; !exists {
+			vars, err := ctx.LinkVariables(pkg)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s cannot be verified (%v)", target, err))
+				failed[pkg] = true
+				continue
+			}
+			packages[pkg] = vars
+		}
+
+		var found *LinkVariable
+		names := []string{}
+		for _, v := range packages[pkg] {
+			if v.Name == name {
+				found = v
+				break
+			}
+			if v.Linkable {
+				names = append(names, v.Name)
+			}
+		}
+
+		if found == nil {
+			msg := fmt.Sprintf("%s does not exist", target)
+			if suggestion := closest(name, names); suggestion != "" {
+				msg += fmt.Sprintf(" (did you mean %s.%s?)", pkg, suggestion)
+			}
+			problems = append(problems, msg)
+		} else if !found.Linkable {
+			problems = append(problems, fmt.Sprintf("%s cannot be set (%s at %s)", target, found.Reason, found.Location()))
+		}
+	}
+
+	return problems
+}
+
+// closest returns the most similar name (for identifying typos).
+func closest(name string, names []string) string {
+	best := ""
+	bestdistance := 3
+	for _, candidate := range names {
+		d := distance(strings.ToLower(name), strings.ToLower(candidate))
+		if d < bestdistance {
+			best = candidate
+			bestdistance = d
+		}
+	}
+
+	return best
+}
+
+// distance is the Levenshtein distance between two strings.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr := make([]int, len(b)+1)
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+
+	return prev[len(b)]
+}
+
+// ValidateVariables reports problems with the manifest variables. The
+// "check_variables" manifest attribute determines whether problems are
+// warnings ("warn", the default), stop the build ("error"), or are not
+// checked at all ("off").
+func (ctx *Context) ValidateVariables() {
+	mode := "warn"
+	if value, exists := ctx.config.Get("check_variables"); exists {
+		switch v := value.(type) {
+		case string:
+			mode = strings.ToLower(v)
+		case bool:
+			if !v {
+				mode = "off"
+			}
+		}
+	}
+
+	if mode == "off" {
+		return
+	}
+
+	problems := ctx.CheckVariables()
+	if len(problems) == 0 {
+		return
+	}
+
+	for _, problem := range problems {
+		if mode == "error" {
+			util.Stderr(fmt.Sprintf("error: %s\n", problem))
+		} else {
+			util.Stderr(fmt.Sprintf("warning: %s\n", problem))
+		}
+	}
+
+	if mode == "error" {
+		util.Stderr("variables cannot be linked (run \"qgo variables\" to list the available variables)\n", true)
+	}
+
+	fmt.Println("")
+}
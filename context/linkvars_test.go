@@ -0,0 +1,131 @@
+package context
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinkVariables(t *testing.T) {
+	ctx := newTestContext(t, `{"name": "app", "build": "main.go"}`,
+		"go.mod", "module example.com/app\n\ngo 1.21\n",
+		"main.go", `package main
+
+import "strings"
+
+const prefix = "v"
+const count = 3
+
+type Version string
+
+var (
+	version    string
+	name       = "app"
+	full       = prefix + "1.0"
+	paren      = (prefix)
+	converted  = string("x")
+	number     = count
+	flag       = true
+	typed      Version
+	computed   = strings.ToUpper("x")
+	imported   = strings.Repeat
+	a, b       = pair()
+	ratio      = 1.5
+	_          = "ignored"
+)
+
+func pair() (string, string) { return "", "" }
+
+func main() {}
+`)
+	ctx.Configure()
+
+	vars, err := ctx.LinkVariables("main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found := map[string]*LinkVariable{}
+	for _, v := range vars {
+		found[v.Name] = v
+	}
+
+	tests := []struct {
+		name     string
+		typ      string
+		linkable bool
+		reason   string
+		value    string
+	}{
+		{name: "version", typ: "string", linkable: true},
+		{name: "name", typ: "string", linkable: true, value: "app"},
+		{name: "full", typ: "string", linkable: true},
+		{name: "paren", typ: "string", linkable: true},
+		{name: "converted", typ: "string", linkable: true},
+		{name: "number", typ: "int", reason: "type int, not string"},
+		{name: "flag", typ: "bool", reason: "type bool, not string"},
+		{name: "typed", typ: "Version", reason: "type Version, not string"},
+		{name: "computed", reason: "initialized to a non-constant value"},
+		{name: "imported", reason: "initialized to a non-constant value"},
+		{name: "a", typ: "string", reason: "initialized to a non-constant value"},
+		{name: "ratio", typ: "float64", reason: "type float64, not string"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, exists := found[test.name]
+			if !exists {
+				t.Fatalf("%s not found", test.name)
+			}
+			if v.Type != test.typ {
+				t.Errorf("expected type %q, got %q", test.typ, v.Type)
+			}
+			if v.Linkable != test.linkable {
+				t.Errorf("expected linkable=%v, got %v (%s)", test.linkable, v.Linkable, v.Reason)
+			}
+			if !strings.Contains(v.Reason, test.reason) {
+				t.Errorf("expected reason %q, got %q", test.reason, v.Reason)
+			}
+			if v.Value != test.value {
+				t.Errorf("expected value %q, got %q", test.value, v.Value)
+			}
+		})
+	}
+
+	if _, exists := found["_"]; exists {
+		t.Error("blank identifiers should be ignored")
+	}
+}
+
+func TestSplitTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		pkg    string
+		name   string
+		ok     bool
+	}{
+		{"main.version", "main", "version", true},
+		{"github.com/org/app/version.Number", "github.com/org/app/version", "Number", true},
+		{"github.com/org/app.v2/pkg", "", "", false},
+		{"version", "", "", false},
+		{"main.", "", "", false},
+		{".version", "", "", false},
+	}
+
+	for _, test := range tests {
+		pkg, name, ok := splitTarget(test.target)
+		if pkg != test.pkg || name != test.name || ok != test.ok {
+			t.Errorf("splitTarget(%q) = (%q, %q, %v), expected (%q, %q, %v)", test.target, pkg, name, ok, test.pkg, test.name, test.ok)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	names := []string{"version", "buildTime", "commit"}
+
+	tests := map[string]string{"verison": "version", "BuildTime": "buildTime", "comit": "commit", "unrelated": ""}
+	for name, expected := range tests {
+		if suggestion := closest(name, names); suggestion != expected {
+			t.Errorf("closest(%q) = %q, expected %q", name, suggestion, expected)
+		}
+	}
+}
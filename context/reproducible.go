@@ -0,0 +1,107 @@
+package context
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quikdev/go/util"
+)
+
+// SourceDateEpoch returns the time used by reproducible builds: the
+// SOURCE_DATE_EPOCH environment variable (a Unix timestamp, see
+// https://reproducible-builds.org/specs/source-date-epoch/), or the time of
+// the last commit.
+func SourceDateEpoch() (time.Time, error) {
+	if epoch := strings.TrimSpace(os.Getenv("SOURCE_DATE_EPOCH")); len(epoch) > 0 {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH \"%s\" (expected a Unix timestamp)", epoch)
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	commit, err := util.Git("log", "-1", "--format=%ct")
+	if err != nil || len(strings.TrimSpace(commit)) == 0 {
+		return time.Time{}, fmt.Errorf("reproducible builds require SOURCE_DATE_EPOCH or a git commit")
+	}
+
+	seconds, err := strconv.ParseInt(strings.TrimSpace(commit), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot read the time of the last commit (%v)", err)
+	}
+
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// useSourceDateEpoch makes the source date epoch the build time, so builds
+// depend only on the source code.
+func useSourceDateEpoch() error {
+	epoch, err := SourceDateEpoch()
+	if err != nil {
+		return err
+	}
+	started = epoch
+
+	return nil
+}
+
+// reproducibleFlags returns the go build flags of reproducible builds: file
+// system paths are removed (-trimpath), and version control information is
+// not stamped (-buildvcs=false), unless "buildvcs" is configured.
+func (ctx *Context) reproducibleFlags() []string {
+	flags := []string{"-trimpath", "-buildvcs=false"}
+	if value, exists := ctx.config.Get("buildvcs"); exists {
+		flags[1] = fmt.Sprintf("-buildvcs=%v", value)
+	}
+
+	return flags
+}
+
+// NormalizeOutput sets the permissions and modification time of the output
+// of a reproducible build, so packaging it (ex: in an archive or an image)
+// is reproducible too. The time of the build is recorded in a stamp file,
+// which determines whether the output is outdated.
+func (ctx *Context) NormalizeOutput() error {
+	if !ctx.Reproducible {
+		return nil
+	}
+
+	mode := os.FileMode(0755)
+	if ctx.WASM {
+		mode = 0644
+	}
+
+	if err := os.Chmod(ctx.Output(), mode); err != nil {
+		return err
+	}
+
+	if err := os.Chtimes(ctx.Output(), buildTime(), buildTime()); err != nil {
+		return err
+	}
+
+	stamp := stampFile(ctx.Output())
+	if err := os.MkdirAll(filepath.Dir(stamp), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(stamp, []byte(ctx.Output()), 0644)
+}
+
+// stampFile returns the path of the stamp file of an output (in the user
+// cache directory, so it is not packaged or exported with the output).
+func stampFile(output string) string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		cache = os.TempDir()
+	}
+
+	output, _ = filepath.Abs(output)
+	sum := sha256.Sum256([]byte(output))
+	return filepath.Join(cache, "qgo", "stamps", hex.EncodeToString(sum[:])[:16])
+}
@@ -0,0 +1,130 @@
+package context
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectLicense(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		text     string
+		expected string
+	}{
+		{"apache", "LICENSE", "Apache License\n  Version 2.0, January 2004", "Apache-2.0"},
+		{"mit", "LICENSE.md", "Permission is hereby granted,\nfree of charge, to any person", "MIT"},
+		{"bsd-3", "LICENSE", "Redistribution and use in source and binary forms ... Neither the name of the copyright holder", "BSD-3-Clause"},
+		{"bsd-2", "license.txt", "Redistribution and use in source and binary forms, with or without modification", "BSD-2-Clause"},
+		{"isc", "LICENSE", "ISC License\n\nCopyright (c)", "ISC"},
+		{"mpl", "LICENCE", "Mozilla Public License Version 2.0", "MPL-2.0"},
+		{"unlicense", "COPYING", "This is free and unencumbered software released into the public domain.", "Unlicense"},
+		{"unknown", "LICENSE", "All rights reserved.", ""},
+		{"not a license file", "README.md", "Permission is hereby granted, free of charge", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, test.file), []byte(test.text), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if license := detectLicense(dir); license != test.expected {
+				t.Errorf("detectLicense() = %q, expected %q", license, test.expected)
+			}
+		})
+	}
+
+	if license := detectLicense(filepath.Join(t.TempDir(), "missing")); license != "" {
+		t.Errorf("detectLicense() = %q for a missing directory", license)
+	}
+}
+
+func TestUpdateSBOM(t *testing.T) {
+	ctx := newTestContext(t, `{"name": "app", "build": "main.go", "sbom": true}`,
+		"go.mod", "module example.com/app\n\ngo 1.21\n",
+		"main.go", "package main\n\nfunc main() {}\n",
+	)
+	ctx.Configure()
+
+	build := exec.Command("go", "build", "-o", ctx.Output(), "main.go")
+	build.Dir = ctx.CWD
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %s", out)
+	}
+
+	sbom, err := ctx.SBOM()
+	if err != nil || sbom == nil {
+		t.Fatalf("SBOM() = %v, %v", sbom, err)
+	}
+	if err := ctx.WriteSBOM(sbom, "go build"); err != nil {
+		t.Fatalf("WriteSBOM() error = %v", err)
+	}
+
+	// The binary changes after the SBOM is written (ex: compressed with UPX)
+	f, err := os.OpenFile(ctx.Output(), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("compressed"))
+	f.Close()
+
+	if err := ctx.UpdateSBOM(sbom); err != nil {
+		t.Fatalf("UpdateSBOM() error = %v", err)
+	}
+
+	hash, size, err := hashFile(ctx.Output())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := ctx.SBOMFiles(sbom)
+	var document struct {
+		Metadata struct {
+			Component struct {
+				Hashes []struct{ Content string }
+			}
+		}
+	}
+	readJSON(t, files[0], &document)
+	if hashes := document.Metadata.Component.Hashes; len(hashes) != 1 || hashes[0].Content != hash {
+		t.Errorf("SBOM hashes = %+v, expected %s", hashes, hash)
+	}
+
+	var provenance struct {
+		Outputs []struct {
+			Path   string
+			SHA256 string
+			Size   int64
+		}
+	}
+	readJSON(t, files[1], &provenance)
+	sbomhash, _, _ := hashFile(files[0])
+	expected := map[string]string{filepath.Base(ctx.Output()): hash, filepath.Base(files[0]): sbomhash}
+	for _, output := range provenance.Outputs {
+		if output.SHA256 != expected[output.Path] {
+			t.Errorf("provenance sha256 of %s = %s, expected %s", output.Path, output.SHA256, expected[output.Path])
+		}
+		if output.Path == filepath.Base(ctx.Output()) && output.Size != size {
+			t.Errorf("provenance size = %d, expected %d", output.Size, size)
+		}
+	}
+	if len(provenance.Outputs) != len(expected) {
+		t.Errorf("provenance outputs = %+v, expected %d", provenance.Outputs, len(expected))
+	}
+}
+
+func readJSON(t *testing.T, file string, value interface{}) {
+	t.Helper()
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(content, value); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,265 @@
+package context
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quikdev/go/util"
+)
+
+// Values retrieved from git/go are cached, since they do not change
+// during the lifetime of the process. Sources are resolved from several
+// goroutines (ex: the dev supervisor and server), so access is synchronized.
+var (
+	cache   = make(map[string]string)
+	cachemu sync.Mutex
+)
+
+var layouts = map[string]string{
+	"ansic":       time.ANSIC,
+	"unixdate":    time.UnixDate,
+	"rubydate":    time.RubyDate,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"rfc850":      time.RFC850,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"kitchen":     time.Kitchen,
+	"datetime":    time.DateTime,
+	"date":        time.DateOnly,
+	"time":        time.TimeOnly,
+}
+
+// Resolve returns the value of a dynamic reference. Supported sources are:
+//
+//	manifest.<attr> (or package.<attr>)  a manifest attribute
+//	env.<name>                           an environment variable
+//	git.commit, git.shortcommit          the current commit hash
+//	git.branch, git.tag                  the current branch/most recent tag
+//	git.dirty                            "true" when there are uncommitted changes
+//	go.version                           the Go toolchain version (ex: go1.21.5)
+//	build.os, build.arch                 the target operating system/architecture
+//	time.<layout>                        the build time (Go layout, name like rfc3339, or unix)
+//	file.<path>                          the (trimmed) content of a file, such as VERSION
+//	cmd.<script>                         the (trimmed) output of a manifest script, run as written (requires "cmd_sources": true)
+//	profile                              the explicitly applied profile(s), joined by "+"
+//
+// The second return value is false when the reference is not a recognized
+// source, in which case the reference should be treated as a literal value.
+// Bare values in "variables" are only resolved for the manifest and env
+// sources (others are written as ${...}, see Configure).
+// Manifest values may contain references themselves, which are resolved by
+// the config (see config.Interpolate).
+func (ctx *Context) Resolve(ref string) (string, bool, error) {
+	if ref == "profile" {
+		return strings.Join(ctx.config.Profiles(), "+"), true, nil
+	}
+
+	prefix, key, found := strings.Cut(ref, ".")
+	if !found || len(key) == 0 {
+		return ref, false, nil
+	}
+
+	switch strings.ToLower(prefix) {
+	case "manifest", "package":
+		if val, exists := ctx.config.GetRaw(strings.Split(key, ".")[0]); exists {
+			if str, ok := val.(string); ok {
+				return str, true, nil
+			}
+			return fmt.Sprintf("%v", val), true, nil
+		}
+		return ref, false, nil
+	case "env":
+		return os.Getenv(key), true, nil
+	case "git":
+		return ctx.gitValue(strings.ToLower(key))
+	case "go":
+		if strings.ToLower(key) != "version" {
+			return ref, false, nil
+		}
+		value, err := cached("go.version", func() (string, error) {
+			out, err := exec.Command("go", "env", "GOVERSION").Output()
+			return strings.TrimSpace(string(out)), err
+		})
+		return value, true, err
+	case "build":
+		switch strings.ToLower(key) {
+		case "os":
+			return ctx.targetOS(), true, nil
+		case "arch":
+			return ctx.targetArch(), true, nil
+		}
+		return ref, false, nil
+	case "time":
+		now := buildTime()
+		if strings.ToLower(key) == "unix" {
+			return fmt.Sprintf("%d", now.Unix()), true, nil
+		}
+		if layout, exists := layouts[strings.ToLower(key)]; exists {
+			return now.Format(layout), true, nil
+		}
+		return now.Format(key), true, nil
+	case "file":
+		content, err := os.ReadFile(key)
+		if err != nil {
+			return "", true, fmt.Errorf("cannot read %s: %v", key, err)
+		}
+		return strings.TrimSpace(string(content)), true, nil
+	case "cmd":
+		// Running scripts is opt-in, since resolving a value should not have side effects by default
+		if enabled, _ := ctx.config.Get("cmd_sources"); enabled != true {
+			return "", true, fmt.Errorf(`cannot resolve %s (set "cmd_sources": true in the manifest to resolve values from scripts)`, ref)
+		}
+		scripts, exists := ctx.config.GetRaw("scripts")
+		if !exists {
+			return "", true, fmt.Errorf(`cannot resolve %s (no scripts defined in the manifest)`, ref)
+		}
+		script, exists := scripts.(map[string]interface{})[key]
+		if !exists {
+			return "", true, fmt.Errorf(`cannot resolve %s ("%s" script does not exist)`, ref, key)
+		}
+		value, err := cached(ref, func() (string, error) {
+			return runScript(script.(string))
+		})
+		return value, true, err
+	}
+
+	return ref, false, nil
+}
+
+// GitKeys are the git.* sources.
+var GitKeys = []string{"commit", "shortcommit", "branch", "tag", "dirty"}
+
+// gitValue returns a git.* source. Values may be provided by the QGO_GIT_<KEY>
+// environment variables (ex: by a process building a copy of the module,
+// which is not a repository).
+func (ctx *Context) gitValue(key string) (string, bool, error) {
+	if util.InSlice[string](key, GitKeys) {
+		if value, exists := os.LookupEnv("QGO_GIT_" + strings.ToUpper(key)); exists {
+			return value, true, nil
+		}
+	}
+
+	var args []string
+	switch key {
+	case "commit":
+		args = []string{"rev-parse", "HEAD"}
+	case "shortcommit":
+		args = []string{"rev-parse", "--short", "HEAD"}
+	case "branch":
+		value, err := cached("git.branch", util.GetGitBranch)
+		return value, true, err
+	case "tag":
+		// An untagged repository is not an error
+		value, _ := cached("git.tag", func() (string, error) {
+			return util.Git("describe", "--tags", "--abbrev=0")
+		})
+		return value, true, nil
+	case "dirty":
+		value, err := cached("git.dirty", func() (string, error) {
+			status, err := util.Git("status", "--porcelain")
+			return fmt.Sprintf("%v", len(status) > 0), err
+		})
+		return value, true, err
+	default:
+		return "git." + key, false, nil
+	}
+
+	value, err := cached("git."+key, func() (string, error) {
+		return util.Git(args...)
+	})
+	return value, true, err
+}
+
+// targetOS returns the operating system being built for.
+func (ctx *Context) targetOS() string {
+	if ctx.isWASM() {
+		return ctx.wasmOS()
+	}
+	if goos := os.Getenv("GOOS"); len(goos) > 0 {
+		return goos
+	}
+	return runtime.GOOS
+}
+
+// targetArch returns the architecture being built for.
+func (ctx *Context) targetArch() string {
+	if ctx.isWASM() {
+		return "wasm"
+	}
+	if goarch := os.Getenv("GOARCH"); len(goarch) > 0 {
+		return goarch
+	}
+	return runtime.GOARCH
+}
+
+func (ctx *Context) isWASM() bool {
+	if ctx.WASM {
+		return true
+	}
+	wasm, exists := ctx.config.Get("wasm")
+	if _, ok := wasm.(map[string]interface{}); ok {
+		return true
+	}
+	return exists && (wasm == true || wasm == "js" || wasm == "wasip1")
+}
+
+// wasmOS returns the GOOS of web assemblies (js or wasip1).
+func (ctx *Context) wasmOS() string {
+	if len(ctx.WASMTarget) > 0 {
+		return ctx.WASMTarget
+	}
+	wasm, _ := ctx.config.Get("wasm")
+	if settings, ok := wasm.(map[string]interface{}); ok {
+		wasm = settings["target"]
+	}
+	if wasm == "wasip1" {
+		return "wasip1"
+	}
+	return "js"
+}
+
+var started = time.Now().UTC()
+
+// buildTime is the time used for all time-based values in a build,
+// so every value is consistent.
+func buildTime() time.Time {
+	return started
+}
+
+func cached(key string, fn func() (string, error)) (string, error) {
+	cachemu.Lock()
+	defer cachemu.Unlock()
+
+	if value, exists := cache[key]; exists {
+		return value, nil
+	}
+
+	value, err := fn()
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve %s: %v", key, err)
+	}
+
+	cache[key] = value
+	return value, nil
+}
+
+func runScript(script string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/c", script)
+	} else {
+		cmd = exec.Command("sh", "-c", script)
+	}
+
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
@@ -0,0 +1,81 @@
+package context
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("QGO_TEST_VALUE", "from env")
+	t.Setenv("QGO_GIT_COMMIT", "0123456789abcdef")
+	t.Setenv("QGO_GIT_DIRTY", "false")
+	t.Setenv("GOOS", "")
+	t.Setenv("GOARCH", "")
+
+	ctx := newTestContext(t, `{"name": "app", "version": "1.0.0", "port": 8080, "scripts": {"hello": "echo hello"}}`, "VERSION", " 2.0.0\n")
+
+	tests := []struct {
+		ref        string
+		expected   string
+		recognized bool
+		err        string
+	}{
+		{ref: "manifest.version", expected: "1.0.0", recognized: true},
+		{ref: "package.name", expected: "app", recognized: true},
+		{ref: "manifest.port", expected: "8080", recognized: true},
+		{ref: "manifest.missing", expected: "manifest.missing"},
+		{ref: "env.QGO_TEST_VALUE", expected: "from env", recognized: true},
+		{ref: "env.QGO_TEST_UNDEFINED", expected: "", recognized: true},
+		{ref: "git.commit", expected: "0123456789abcdef", recognized: true},
+		{ref: "git.dirty", expected: "false", recognized: true},
+		{ref: "git.unknown", expected: "git.unknown"},
+		{ref: "build.os", expected: runtime.GOOS, recognized: true},
+		{ref: "build.arch", expected: runtime.GOARCH, recognized: true},
+		{ref: "build.other", expected: "build.other"},
+		{ref: "time.unix", expected: fmt.Sprintf("%d", buildTime().Unix()), recognized: true},
+		{ref: "time.date", expected: buildTime().Format("2006-01-02"), recognized: true},
+		{ref: "time.2006", expected: buildTime().Format("2006"), recognized: true},
+		{ref: "file.VERSION", expected: "2.0.0", recognized: true},
+		{ref: "file.MISSING", recognized: true, err: "cannot read MISSING"},
+		{ref: "cmd.hello", recognized: true, err: `set "cmd_sources": true`},
+		{ref: "profile", expected: "", recognized: true},
+		{ref: "literal", expected: "literal"},
+		{ref: "other.value", expected: "other.value"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ref, func(t *testing.T) {
+			value, recognized, err := ctx.Resolve(test.ref)
+			if len(test.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if value != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, value)
+			}
+			if recognized != test.recognized {
+				t.Errorf("expected recognized=%v, got %v", test.recognized, recognized)
+			}
+		})
+	}
+}
+
+func TestResolveCommandSources(t *testing.T) {
+	ctx := newTestContext(t, `{"cmd_sources": true, "scripts": {"hello": "echo hello"}}`)
+
+	value, _, err := ctx.Resolve("cmd.hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "hello" {
+		t.Errorf("expected hello, got %q", value)
+	}
+
+	if _, _, err := ctx.Resolve("cmd.missing"); err == nil || !strings.Contains(err.Error(), `"missing" script does not exist`) {
+		t.Errorf("expected a missing script error, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package context
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WASI runtimes, in order of preference
+var wasiruntimes = []string{"wasmtime", "wazero"}
+
+// WASI determines whether the app is a WASI module ("wasm": "wasip1").
+func (ctx *Context) WASI() bool {
+	return ctx.WASM && ctx.wasmOS() == "wasip1"
+}
+
+// Browser determines whether the app is a web assembly served to the browser
+// (rather than a WASI module, or a js/wasm binary run with Node.js).
+func (ctx *Context) Browser() bool {
+	return ctx.WASM && !ctx.WASI() && ctx.WASMRuntime != "node"
+}
+
+// WASIRuntime returns the command (without the module) that runs a WASI
+// module with a locally installed runtime. The runtime is configured with the
+// "wasi" manifest attribute:
+//
+//	"wasi": {
+//	  "runtime": "wazero",   // wasmtime or wazero (defaults to the first one installed)
+//	  "dirs": [".", "data"]  // Preopened directories (defaults to the working directory)
+//	}
+//
+// The environment variables of the app are passed to the module.
+func (ctx *Context) WASIRuntime() ([]string, error) {
+	name := ""
+	dirs := []string{"."}
+
+	if value, exists := ctx.config.Get("wasi"); exists {
+		wasi, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{}, fmt.Errorf(`invalid "wasi" value (expected an object)`)
+		}
+
+		if runtime, ok := wasi["runtime"].(string); ok {
+			name = runtime
+		}
+
+		if list, ok := wasi["dirs"].([]interface{}); ok {
+			dirs = []string{}
+			for _, item := range list {
+				if dir, ok := item.(string); ok {
+					dirs = append(dirs, dir)
+				}
+			}
+		}
+	}
+
+	candidates := wasiruntimes
+	if len(name) > 0 {
+		candidates = []string{name}
+	}
+
+	runtime := ""
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate); err == nil {
+			runtime = candidate
+			break
+		}
+	}
+
+	if len(runtime) == 0 {
+		if len(name) > 0 {
+			return []string{}, fmt.Errorf("the %s WASI runtime is not installed", name)
+		}
+		return []string{}, fmt.Errorf("a WASI runtime is required to run wasip1 modules (install %s)", strings.Join(wasiruntimes, " or "))
+	}
+
+	vars, err := ctx.config.Environment()
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Values are inherited from the environment of the runtime, so they are
+	// not displayed in the command (which could reveal secrets).
+	args := []string{runtime, "run"}
+	switch runtime {
+	case "wazero":
+		args = append(args, "-env-inherit")
+		for _, dir := range dirs {
+			args = append(args, "-mount="+dir+":"+guestPath(dir))
+		}
+	default:
+		for _, dir := range dirs {
+			args = append(args, "--dir="+dir)
+		}
+		for _, v := range vars {
+			if v.Source != "process" {
+				args = append(args, "--env", v.Name)
+			}
+		}
+	}
+
+	return args, nil
+}
+
+// guestPath maps a preopened directory into the module file system (relative
+// directories are mounted under the root, which is the working directory of
+// the module).
+func guestPath(dir string) string {
+	if filepath.IsAbs(dir) {
+		return filepath.ToSlash(dir)
+	}
+
+	// Parent directories cannot be mounted above the root (ex: ../data is /data)
+	return path.Join("/", filepath.ToSlash(dir))
+}
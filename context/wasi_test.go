@@ -0,0 +1,34 @@
+package context
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGuestPath(t *testing.T) {
+	tests := []struct {
+		dir      string
+		expected string
+	}{
+		{".", "/"},
+		{"./", "/"},
+		{"data", "/data"},
+		{"./data", "/data"},
+		{"data/", "/data"},
+		{"./data/../assets", "/assets"},
+		{".cache", "/.cache"},
+		{"./.config/app", "/.config/app"},
+		{"..data", "/..data"},
+		{"../data", "/data"},
+	}
+
+	for _, test := range tests {
+		if guest := guestPath(filepath.FromSlash(test.dir)); guest != test.expected {
+			t.Errorf("guestPath(%q) = %q, expected %q", test.dir, guest, test.expected)
+		}
+	}
+
+	if abs, _ := filepath.Abs("data"); guestPath(abs) != filepath.ToSlash(abs) {
+		t.Errorf("guestPath(%q) = %q, expected the absolute path", abs, guestPath(abs))
+	}
+}
@@ -0,0 +1,121 @@
+package context
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/quikdev/go/util"
+)
+
+// WASMExec returns the path of the wasm_exec.js file distributed with the
+// installed toolchain (TinyGo when tiny is true), along with the toolchain
+// version. The file must match the toolchain that compiled the WASM file, or
+// the browser fails at runtime.
+func WASMExec(tiny bool) (string, string, error) {
+	if tiny {
+		root, err := goenv("tinygo", "TINYGOROOT")
+		if err != nil {
+			return "", "", fmt.Errorf("cannot locate TinyGo (%v)", err)
+		}
+
+		version := "tinygo"
+		if out, err := exec.Command("tinygo", "version").Output(); err == nil {
+			version = strings.TrimSpace(string(out))
+		}
+
+		file := filepath.Join(root, "targets", "wasm_exec.js")
+		if !util.FileExists(file) {
+			return "", version, fmt.Errorf("wasm_exec.js not found in %s", root)
+		}
+
+		return file, version, nil
+	}
+
+	version, _ := goenv("go", "GOVERSION")
+	file, err := gorootWASM("wasm_exec.js")
+
+	return file, version, err
+}
+
+// WASMNode returns the command that runs a js/wasm binary with Node.js, using
+// the wasm_exec_node.js loader of the installed Go toolchain.
+func WASMNode() ([]string, error) {
+	if _, err := exec.LookPath("node"); err != nil {
+		return []string{}, fmt.Errorf("Node.js is required to run web assemblies outside of the browser (node not found)")
+	}
+
+	loader, err := gorootWASM("wasm_exec_node.js")
+	if err != nil {
+		return []string{}, err
+	}
+
+	return []string{"node", loader}, nil
+}
+
+// gorootWASM returns the path of a WASM support file of the Go toolchain.
+func gorootWASM(name string) (string, error) {
+	root, err := goenv("go", "GOROOT")
+	if err != nil {
+		return "", fmt.Errorf("cannot locate GOROOT (%v)", err)
+	}
+
+	// Go 1.24 moved the files from misc/wasm to lib/wasm
+	for _, dir := range []string{filepath.Join("lib", "wasm"), filepath.Join("misc", "wasm")} {
+		file := filepath.Join(root, dir, name)
+		if util.FileExists(file) {
+			return file, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s not found in %s", name, root)
+}
+
+func goenv(toolchain string, name string) (string, error) {
+	out, err := exec.Command(toolchain, "env", name).Output()
+	if err != nil {
+		return "", err
+	}
+
+	value := strings.TrimSpace(string(out))
+	if len(value) == 0 {
+		return "", fmt.Errorf("%s is not set", name)
+	}
+
+	return value, nil
+}
+
+// UpdateWASMExec copies the wasm_exec.js file of the toolchain to the output
+// directory, unless the existing file already matches it (i.e. the toolchain
+// has not changed). It returns true when the file was written.
+func (ctx *Context) UpdateWASMExec() (bool, error) {
+	source, version, err := WASMExec(ctx.Tiny)
+	if err != nil {
+		return false, err
+	}
+
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return false, err
+	}
+
+	dest := filepath.Join(filepath.Dir(ctx.Output()), "wasm_exec.js")
+	if existing, err := os.ReadFile(dest); err == nil && bytes.Equal(existing, content) {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return false, err
+	}
+
+	if err := os.WriteFile(dest, content, 0644); err != nil {
+		return false, err
+	}
+
+	util.Stdout(fmt.Sprintf("# copied wasm_exec.js (%s) to %s\n", version, dest))
+
+	return true, nil
+}
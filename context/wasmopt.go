@@ -0,0 +1,197 @@
+package context
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/dustin/go-humanize"
+	"github.com/quikdev/go/util"
+)
+
+// WASM features emitted by the Go toolchain (and TinyGo), which wasm-opt must
+// accept and preserve
+var wasmfeatures = []string{"--enable-sign-ext", "--enable-nontrapping-float-to-int", "--enable-mutable-globals", "--enable-bulk-memory"}
+
+// WASMOptimization configures the size optimization of web assemblies
+// ("wasm": {"optimize": ...}). wasm-opt is skipped when Level is empty.
+type WASMOptimization struct {
+	Level  string   `json:"level,omitempty"` // wasm-opt optimization level (ex: Oz)
+	Flags  []string `json:"flags,omitempty"` // Additional wasm-opt flags
+	Gzip   bool     `json:"gzip"`            // Write a precompressed .wasm.gz file
+	Brotli bool     `json:"brotli"`          // Write a precompressed .wasm.br file
+}
+
+// parseWASMOptimization reads the optimize attribute of the wasm block, which
+// is a boolean, a wasm-opt level, or an object:
+//
+//	"optimize": true
+//	"optimize": "O3"
+//	"optimize": {"level": "Oz", "flags": ["--converge"], "gzip": true, "brotli": false}
+func parseWASMOptimization(value interface{}) (*WASMOptimization, error) {
+	optimization := &WASMOptimization{Level: "Oz", Gzip: true, Brotli: true}
+
+	switch v := value.(type) {
+	case bool:
+		if !v {
+			return nil, nil
+		}
+	case string:
+		optimization.Level = v
+	case map[string]interface{}:
+		switch level := v["level"].(type) {
+		case string:
+			optimization.Level = level
+		case bool:
+			if !level {
+				optimization.Level = ""
+			}
+		}
+		if flags, ok := v["flags"].([]interface{}); ok {
+			for _, flag := range flags {
+				optimization.Flags = append(optimization.Flags, fmt.Sprintf("%v", flag))
+			}
+		}
+		if gz, ok := v["gzip"].(bool); ok {
+			optimization.Gzip = gz
+		}
+		if br, ok := v["brotli"].(bool); ok {
+			optimization.Brotli = br
+		}
+	default:
+		return nil, fmt.Errorf(`invalid wasm "optimize" value (expected a boolean, a wasm-opt level, or an object)`)
+	}
+
+	optimization.Level = strings.TrimLeft(optimization.Level, "-")
+	switch optimization.Level {
+	case "", "O", "O0", "O1", "O2", "O3", "O4", "Os", "Oz":
+	default:
+		return nil, fmt.Errorf(`invalid wasm-opt level "%s" (expected O0-O4, Os, or Oz)`, optimization.Level)
+	}
+
+	return optimization, nil
+}
+
+// OptimizeWASM runs wasm-opt on the web assembly (when installed) and writes
+// the precompressed .wasm.gz/.wasm.br files used by the dev server and for
+// deployment. The size of each file is displayed.
+func (ctx *Context) OptimizeWASM() error {
+	if ctx.WASMOptimize == nil {
+		return nil
+	}
+
+	output := ctx.Output()
+	info, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+	original := uint64(info.Size())
+
+	util.Stdout("\n# optimizing web assembly\n")
+	type row struct {
+		label string
+		size  uint64
+	}
+	sizes := []row{{"Original", original}}
+
+	if len(ctx.WASMOptimize.Level) > 0 {
+		if _, err := exec.LookPath("wasm-opt"); err != nil {
+			util.Stderr("wasm-opt not found (install binaryen to optimize web assemblies)\n")
+		} else {
+			args := append([]string{"-" + ctx.WASMOptimize.Level}, wasmfeatures...)
+			args = append(args, ctx.WASMOptimize.Flags...)
+			// Display the output relative to the working directory (like go build)
+			file := output
+			if base, err := filepath.Abs(ctx.CWD); err == nil {
+				if rel, err := filepath.Rel(base, output); err == nil && !strings.HasPrefix(rel, "..") {
+					file = "." + string(filepath.Separator) + rel
+				}
+			}
+			args = append(args, "-o", file, file)
+
+			util.HighlightCommand("wasm-opt", args...)
+			cmd := exec.Command("wasm-opt", args...)
+			cmd.Dir = ctx.CWD
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("wasm-opt failed (%v)", err)
+			}
+
+			if info, err = os.Stat(output); err != nil {
+				return err
+			}
+			sizes = append(sizes, row{"wasm-opt -" + ctx.WASMOptimize.Level, uint64(info.Size())})
+		}
+	}
+
+	if ctx.WASMOptimize.Gzip {
+		size, err := precompress(output, "gzip")
+		if err != nil {
+			return err
+		}
+		sizes = append(sizes, row{"gzip", size})
+	}
+
+	if ctx.WASMOptimize.Brotli {
+		size, err := precompress(output, "br")
+		if err != nil {
+			return err
+		}
+		sizes = append(sizes, row{"brotli", size})
+	}
+
+	for _, r := range sizes {
+		util.Stdout(fmt.Sprintf("  ↳ %-16s %9s  %3d%% of original\n", r.label+":", humanize.Bytes(r.size), r.size*100/original))
+	}
+
+	return nil
+}
+
+// precompress writes a compressed copy of a file (file.gz or file.br) at the
+// highest compression level, returning its size.
+func precompress(file string, encoding string) (uint64, error) {
+	source, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+
+	name := file + ".gz"
+	if encoding == "br" {
+		name = file + ".br"
+	}
+
+	dest, err := os.Create(name)
+	if err != nil {
+		return 0, err
+	}
+	defer dest.Close()
+
+	var writer io.WriteCloser
+	if encoding == "br" {
+		writer = brotli.NewWriterLevel(dest, brotli.BestCompression)
+	} else {
+		writer, _ = gzip.NewWriterLevel(dest, gzip.BestCompression)
+	}
+
+	if _, err := io.Copy(writer, source); err != nil {
+		writer.Close()
+		return 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	info, err := dest.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(info.Size()), nil
+}
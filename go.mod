@@ -3,9 +3,11 @@ module github.com/quikdev/go
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/Masterminds/semver v1.5.0
 	github.com/alecthomas/kong v0.8.1
 	github.com/alegrey91/go-upx v0.2.1
+	github.com/andybalholm/brotli v1.1.1
 	github.com/charmbracelet/huh v0.3.0
 	github.com/coreybutler/go-fsutil v1.2.1
 	github.com/dustin/go-humanize v1.0.1
@@ -14,6 +16,7 @@ require (
 	github.com/logrusorgru/aurora/v3 v3.0.0
 	github.com/mpontillo/tap13 v1.0.2
 	github.com/peterbourgon/mergemap v0.0.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,74 @@
+// Package hook provides the build event received by prebuild, postbuild,
+// prerun, and postrun hooks. Hooks written in Go can read the event with:
+//
+//	event, err := hook.Read()
+//	if err == nil && event.Stage == hook.PostBuild && event.Success {
+//		fmt.Printf("built %s in %s\n", event.Output, event.Duration())
+//	}
+package hook
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Hook stages
+const (
+	PreBuild  = "prebuild"
+	PostBuild = "postbuild"
+	PreRun    = "prerun"
+	PostRun   = "postrun"
+)
+
+// Event describes the build (or run) a hook is executed for. It is written
+// to the standard input of the hook as JSON, and provided in QGO_* environment
+// variables (see Env).
+type Event struct {
+	Stage      string `json:"stage"`            // prebuild, postbuild, prerun, or postrun
+	Target     string `json:"target,omitempty"` // Build target (if any)
+	Name       string `json:"name"`             // Application name
+	Version    string `json:"version,omitempty"`
+	Output     string `json:"output"` // Path of the executable (or WASM file)
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	DurationMS int64  `json:"duration_ms"` // Duration of the build/run (post hooks only)
+	Success    bool   `json:"success"`     // Whether the build/run succeeded (post hooks only)
+}
+
+// Duration returns the duration of the build/run.
+func (e *Event) Duration() time.Duration {
+	return time.Duration(e.DurationMS) * time.Millisecond
+}
+
+// Env returns the event as environment variables (ex: QGO_OUTPUT=./bin/app).
+func (e *Event) Env() []string {
+	return []string{
+		"QGO_HOOK=" + e.Stage,
+		"QGO_TARGET=" + e.Target,
+		"QGO_NAME=" + e.Name,
+		"QGO_VERSION=" + e.Version,
+		"QGO_OUTPUT=" + e.Output,
+		"QGO_OS=" + e.OS,
+		"QGO_ARCH=" + e.Arch,
+		"QGO_DURATION_MS=" + strconv.FormatInt(e.DurationMS, 10),
+		"QGO_SUCCESS=" + strconv.FormatBool(e.Success),
+	}
+}
+
+// Read decodes the event from the standard input.
+func Read() (*Event, error) {
+	return Decode(os.Stdin)
+}
+
+// Decode decodes an event from a reader.
+func Decode(r io.Reader) (*Event, error) {
+	event := &Event{}
+	if err := json.NewDecoder(r).Decode(event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
@@ -62,6 +62,36 @@ func FindGoWorkFile(path string) (string, bool) {
 	return "", false
 }
 
+func FindModuleRoot(path string) (string, bool) {
+	// Check if the path is empty
+	if path == "" {
+		return "", false
+	}
+
+	// Get the absolute path of the input
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+
+	// Iterate over parent directories until a go.mod file is found
+	for {
+		if FileExists(filepath.Join(absPath, "go.mod")) {
+			return absPath, true
+		}
+
+		// Move to the parent directory
+		parent := filepath.Dir(absPath)
+		if parent == absPath {
+			// Reached the volume root
+			break
+		}
+		absPath = parent
+	}
+
+	return "", false
+}
+
 func FindMainFileInDirectory(directoryPath string) (string, error) {
 	// Create a list of .go files in the specified directory
 	goFiles := []string{}
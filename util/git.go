@@ -0,0 +1,41 @@
+package util
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Git runs a git command in the current working directory and
+// returns the trimmed output.
+func Git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// GetGitBranch returns the name of the current git branch. When HEAD is
+// detached (common in CI), common CI environment variables are checked.
+func GetGitBranch() (string, error) {
+	branch, err := Git("symbolic-ref", "--short", "HEAD")
+	if err == nil && len(branch) > 0 {
+		return branch, nil
+	}
+
+	for _, name := range []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME", "CI_COMMIT_REF_NAME", "BRANCH_NAME", "GIT_BRANCH"} {
+		if value := os.Getenv(name); len(value) > 0 {
+			return value, nil
+		}
+	}
+
+	return branch, err
+}
+
+// IsCI determines whether the process is running in a continuous integration environment.
+func IsCI() bool {
+	value := strings.ToLower(os.Getenv("CI"))
+	return value == "true" || value == "1"
+}
@@ -3,6 +3,7 @@ package util
 import (
 	"errors"
 	"os"
+	"strings"
 )
 
 // InsertArgAt inserts an argument into os.Args at the specified position
@@ -32,6 +33,48 @@ func RemoveArgAt(index int) error {
 	return nil
 }
 
+// Subcommand returns the qgo command (ex: run) and the arguments that follow
+// it. The root --manifest flag is removed from the arguments, wherever it is
+// (ex: qgo --manifest app.json run), except after the "--" separator.
+func Subcommand(args []string) (string, []string) {
+	command := ""
+	remaining := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			if len(command) == 0 {
+				return command, remaining
+			}
+			return command, append(remaining, args[i:]...)
+		case arg == "--manifest":
+			i++
+			continue
+		case strings.HasPrefix(arg, "--manifest="):
+			continue
+		case len(command) == 0 && !strings.HasPrefix(arg, "-"):
+			command = arg
+			continue
+		}
+
+		if len(command) > 0 {
+			remaining = append(remaining, arg)
+		}
+	}
+
+	return command, remaining
+}
+
+// CurrentCommand returns the qgo command being run (see Subcommand).
+func CurrentCommand() string {
+	if len(os.Args) < 2 {
+		return ""
+	}
+
+	command, _ := Subcommand(os.Args[1:])
+	return command
+}
+
 // IndexOf returns the position of the specified item
 func IndexOf[V comparable](slice []V, target V) int {
 	for i, v := range slice {
@@ -0,0 +1,35 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubcommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		command  string
+		expected []string
+	}{
+		{"command only", []string{"run"}, "run", []string{}},
+		{"arguments", []string{"run", "main.go", "--port", "8080"}, "run", []string{"main.go", "--port", "8080"}},
+		{"manifest before the command", []string{"--manifest", "app.json", "run", "server"}, "run", []string{"server"}},
+		{"manifest with equal sign", []string{"--manifest=app.json", "build"}, "build", []string{}},
+		{"manifest after the command", []string{"run", "--manifest", "app.json", "-a"}, "run", []string{"-a"}},
+		{"manifest after the separator", []string{"run", "--", "--manifest", "x"}, "run", []string{"--", "--manifest", "x"}},
+		{"no command", []string{"--manifest", "app.json"}, "", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			command, args := Subcommand(test.args)
+			if command != test.command {
+				t.Errorf("expected command %q, got %q", test.command, command)
+			}
+			if !reflect.DeepEqual(args, test.expected) {
+				t.Errorf("expected arguments %q, got %q", test.expected, args)
+			}
+		})
+	}
+}